@@ -3,21 +3,28 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/lag13/records/internal/endpoints/compact"
+	"github.com/lag13/records/internal/endpoints/getgroups"
 	"github.com/lag13/records/internal/endpoints/getsortperson"
+	"github.com/lag13/records/internal/endpoints/getstats"
 	"github.com/lag13/records/internal/endpoints/postrecord"
+	"github.com/lag13/records/internal/endpoints/recordbyid"
+	"github.com/lag13/records/internal/endpoints/searchperson"
+	"github.com/lag13/records/internal/endpoints/upcomingbirthdays"
+	"github.com/lag13/records/internal/multicsv"
 	"github.com/lag13/records/internal/person"
-)
-
-var (
-	db = []person.Person{}
-	mu = &sync.Mutex{}
+	"github.com/lag13/records/internal/response"
+	"github.com/lag13/records/internal/store"
 )
 
 func writeAndLogErr(w http.ResponseWriter, body []byte) {
@@ -26,67 +33,257 @@ func writeAndLogErr(w http.ResponseWriter, body []byte) {
 	}
 }
 
-func main() {
+// writeResponse writes the headers, status code and JSON encoded body
+// of a structured response.
+func writeResponse(w http.ResponseWriter, resp response.Structured) {
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if resp.StatusCode == http.StatusNoContent {
+		return
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		// The only time json.Marshal fails is if a type is
+		// passed in which cannot be marshalled so, to me, a
+		// panic is acceptable here.
+		panic(err)
+	}
+	writeAndLogErr(w, body)
+}
+
+// storeErrResponse is returned when the store could not be read.
+var storeErrResponse = response.Structured{
+	StatusCode: http.StatusInternalServerError,
+	Errors:     []string{"unexpected error"},
+}
+
+// sortHandler returns the stored records sorted in the given order.
+// If the store can return records already sorted then it is left to
+// do so, otherwise sortFn does the sorting.
+func sortHandler(s store.Store, order store.Order, sortFn func([]person.Person)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fn := sortFn
+		list := s.List
+		if sorter, ok := s.(store.Sorter); ok {
+			fn = func([]person.Person) {}
+			list = func() ([]person.Person, error) { return sorter.ListSorted(order) }
+		}
+		ps, err := list()
+		if err != nil {
+			log.Print(err)
+			writeResponse(w, storeErrResponse)
+			return
+		}
+		writeResponse(w, getsortperson.Sort(r, fn, ps))
+	}
+}
+
+func compactHandler(c compact.Compactor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, err := compact.Compact(r, c)
+		if err != nil {
+			log.Print(err)
+		}
+		writeResponse(w, resp)
+	}
+}
+
+// newMux returns the API's routes.
+func newMux(s store.Store, d multicsv.Dialect, dateFormats []string) *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 	mux.HandleFunc("/records", func(w http.ResponseWriter, r *http.Request) {
-		p, resp, err := postrecord.PostRecord(r)
+		if r.Method == http.MethodGet {
+			ps, err := s.List()
+			if err != nil {
+				log.Print(err)
+				writeResponse(w, storeErrResponse)
+				return
+			}
+			writeResponse(w, getsortperson.SortBySpec(r, ps))
+			return
+		}
+		resp, err := postrecord.PostRecord(r, s, d, dateFormats)
 		if err != nil {
 			log.Print(err)
 		}
-		w.WriteHeader(resp.StatusCode)
-		if len(resp.Errors) == 0 {
-			mu.Lock()
-			defer mu.Unlock()
-			db = append(db, p)
-			return
+		writeResponse(w, resp)
+	})
+	mux.HandleFunc("/records/", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := recordbyid.Handle(r, s, d, dateFormats)
+		if err != nil {
+			log.Print(err)
 		}
-		// TODO: This json encoding logic is duplicated in
-		// other places and should be consolidated. More
-		// generally, the logic of writing the response is
-		// duplicated and could be consolidated.
-		body, err := json.Marshal(resp)
+		writeResponse(w, resp)
+	})
+	mux.HandleFunc("/records/search", func(w http.ResponseWriter, r *http.Request) {
+		ps, err := s.List()
 		if err != nil {
-			// The only time json.Marshal fails is if a
-			// type is passed in which cannot be
-			// marshalled so, to me, a panic is acceptable
-			// here.
-			panic(err)
+			log.Print(err)
+			writeResponse(w, storeErrResponse)
+			return
 		}
-		writeAndLogErr(w, body)
+		writeResponse(w, searchperson.Search(r, ps))
 	})
-	mux.HandleFunc("/records/gender", func(w http.ResponseWriter, r *http.Request) {
-		resp := getsortperson.Sort(r, person.SortGenderLastNameAsc, db)
-		w.WriteHeader(resp.StatusCode)
-		body, err := json.Marshal(resp)
+	mux.HandleFunc("/records/stats", func(w http.ResponseWriter, r *http.Request) {
+		ps, err := s.List()
 		if err != nil {
-			panic(err)
+			log.Print(err)
+			writeResponse(w, storeErrResponse)
+			return
 		}
-		writeAndLogErr(w, body)
+		writeResponse(w, getstats.Stats(r, ps, time.Now()))
 	})
-	mux.HandleFunc("/records/birthdate", func(w http.ResponseWriter, r *http.Request) {
-		resp := getsortperson.Sort(r, person.SortBirthdateAsc, db)
-		w.WriteHeader(resp.StatusCode)
-		body, err := json.Marshal(resp)
+	mux.HandleFunc("/records/groups", func(w http.ResponseWriter, r *http.Request) {
+		ps, err := s.List()
 		if err != nil {
-			panic(err)
+			log.Print(err)
+			writeResponse(w, storeErrResponse)
+			return
 		}
-		writeAndLogErr(w, body)
+		writeResponse(w, getgroups.Groups(r, ps))
 	})
-	mux.HandleFunc("/records/name", func(w http.ResponseWriter, r *http.Request) {
-		resp := getsortperson.Sort(r, person.SortLastNameDesc, db)
-		w.WriteHeader(resp.StatusCode)
-		body, err := json.Marshal(resp)
+	mux.HandleFunc("/records/birthdays/upcoming", func(w http.ResponseWriter, r *http.Request) {
+		ps, err := s.List()
 		if err != nil {
-			panic(err)
+			log.Print(err)
+			writeResponse(w, storeErrResponse)
+			return
 		}
-		writeAndLogErr(w, body)
+		writeResponse(w, upcomingbirthdays.Upcoming(r, ps, time.Now()))
 	})
+	mux.HandleFunc("/records/gender", sortHandler(s, store.OrderGenderLastNameAsc, person.SortGenderLastNameAsc))
+	mux.HandleFunc("/records/birthdate", sortHandler(s, store.OrderBirthdateAsc, person.SortBirthdateAsc))
+	mux.HandleFunc("/records/name", sortHandler(s, store.OrderLastNameDesc, person.SortLastNameDesc))
+	return mux
+}
+
+// newAdminMux returns the administrative routes. They are served on
+// their own listener because anyone who can reach them can, for
+// example, block writes by compacting over and over.
+func newAdminMux(c compact.Compactor) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/compact", compactHandler(c))
+	return mux
+}
+
+// openStore returns the store the API will use. If no data
+// directory was configured then records are only kept in memory.
+func openStore(dataDir string, kind string) (store.Store, func() error, error) {
+	if dataDir == "" {
+		return store.NewMemory(), func() error { return nil }, nil
+	}
+	switch kind {
+	case "log":
+	case "sqlite":
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return nil, nil, err
+		}
+		db, err := store.OpenSQLite(filepath.Join(dataDir, store.SQLiteFileName))
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, db.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown store %q, allowed values are log, sqlite", kind)
+	}
+	l, err := store.OpenLog(dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n := l.Discarded(); n > 0 {
+		log.Printf("discarded %d bytes of a partially written entry at the end of the log in %s", n, dataDir)
+	}
+	return l, l.Close, nil
+}
+
+// compactPeriodically compacts c every interval until stop is closed
+// at which point done is closed.
+func compactPeriodically(c compact.Compactor, interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Compact(); err != nil {
+				log.Printf("compacting: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func main() {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	dataDir := fs.String("data-dir", "", "directory where records are persisted, if empty records are only kept in memory")
+	storeKind := fs.String("store", "log", "how records are persisted in the data directory, either log or sqlite")
+	dialectPath := fs.String("dialect", "", "JSON file describing the format of posted records, see multicsv.LoadDialect, if empty pipe, comma and space separated records are accepted")
+	dateFormatsStr := fs.String("date-formats", strings.Join(person.DefaultDateFormats, ","), fmt.Sprintf("comma separated list of the formats, out of %s, a posted date of birth can be in, requests can override it with the %s query parameter", strings.Join(person.DateFormatNames(), ", "), person.DateFormatsParam))
+	compactInterval := fs.Duration("compact-interval", 10*time.Minute, "how often persisted records are snapshotted and the log emptied, 0 disables it")
+	adminAddr := fs.String("admin-addr", "", "address, like localhost:8081, on which to serve /admin/compact, which should not be reachable by clients, if empty it is not served")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	dialect := multicsv.DefaultDialect
+	if *dialectPath != "" {
+		var err error
+		if dialect, err = multicsv.LoadDialect(*dialectPath); err != nil {
+			log.Fatal(err)
+		}
+		if dialect.NumFields != person.NumFields {
+			log.Fatalf("invalid dialect %s: records have %d fields, not %d", *dialectPath, person.NumFields, dialect.NumFields)
+		}
+	}
+	dateFormats, err := person.ParseDateFormats(*dateFormatsStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, closeStore, err := openStore(*dataDir, *storeKind)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, _ := s.(compact.Compactor)
+	if _, ok := s.(store.Sorter); !ok {
+		// Keep the records sorted in the orders the API serves
+		// instead of sorting them on every request.
+		s, err = store.NewIndexed(s, store.OrderGenderLastNameAsc, store.OrderBirthdateAsc, store.OrderLastNameDesc)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	stopCompacting := make(chan struct{})
+	compactingStopped := make(chan struct{})
+	if c != nil && *compactInterval > 0 {
+		go compactPeriodically(c, *compactInterval, stopCompacting, compactingStopped)
+	} else {
+		close(compactingStopped)
+	}
 	srv := http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: newMux(s, dialect, dateFormats),
+	}
+	var adminSrv *http.Server
+	if *adminAddr != "" {
+		if c == nil {
+			log.Fatal("-admin-addr needs records to be persisted in a log, see -data-dir and -store")
+		}
+		adminSrv = &http.Server{
+			Addr:    *adminAddr,
+			Handler: newAdminMux(c),
+		}
+		go func() {
+			if err := adminSrv.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatalf("admin HTTP server ListenAndServe: %v", err)
+			}
+		}()
 	}
 	idleConnsClosed := make(chan struct{})
 	go func() {
@@ -95,6 +292,11 @@ func main() {
 		<-sigint
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 		defer cancel()
+		if adminSrv != nil {
+			if err := adminSrv.Shutdown(ctx); err != nil {
+				log.Printf("admin HTTP server Shutdown: %v", err)
+			}
+		}
 		if err := srv.Shutdown(ctx); err != nil {
 			log.Printf("HTTP server Shutdown: %v", err)
 		}
@@ -104,4 +306,9 @@ func main() {
 		log.Printf("HTTP server ListenAndServe: %v", err)
 	}
 	<-idleConnsClosed
+	close(stopCompacting)
+	<-compactingStopped
+	if err := closeStore(); err != nil {
+		log.Print(err)
+	}
 }
@@ -1,15 +1,23 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/lag13/records/internal/birthdays"
+	"github.com/lag13/records/internal/fields"
 	"github.com/lag13/records/internal/multicsv"
 	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/sortspec"
+	"github.com/lag13/records/internal/where"
 )
 
 // TODO: I feel like calls to this function should happen in one
@@ -34,7 +42,7 @@ func prependFileInfo(fileName string, lineNum int, msgs []string) []string {
 // TODO: This logic feels too complicated especially for main. Maybe I
 // just need to put more of the loops into the units. God I wish Go
 // had map and other such operations which operate on collections.
-func parseDataFromFiles(fileNames []string) ([]person.Person, []string) {
+func parseDataFromFiles(fileNames []string, d multicsv.Dialect, formats dateFormats) ([]person.Person, []string) {
 	type simpleFile struct {
 		Name    string
 		Content io.Reader
@@ -69,12 +77,10 @@ func parseDataFromFiles(fileNames []string) ([]person.Person, []string) {
 	if len(files) == 0 {
 		files = append(files, simpleFile{Name: "(standard input)", Content: os.Stdin})
 	}
-	filesRecords := [][][]string{}
+	filesRecords := [][]multicsv.Record{}
 	{ // validate the syntax of the data
-		const possibleDelimiters = "|, "
-		const numFieldsInRecord = 5
 		for _, file := range files {
-			records, csvParseErrs := multicsv.ReadAll(possibleDelimiters, numFieldsInRecord, file.Content)
+			records, csvParseErrs := d.ReadAll(file.Content)
 			if len(csvParseErrs) > 0 {
 				parseErrs = append(parseErrs, prependFileInfo(file.Name, 0, csvParseErrs)...)
 				continue
@@ -97,10 +103,14 @@ func parseDataFromFiles(fileNames []string) ([]person.Person, []string) {
 	persons := []person.Person{}
 	{ // parse each file into structured data which can be sorted
 		for i, file := range files {
-			for j, line := range filesRecords[i] {
-				p, semParseErrs := person.Parse(line)
+			lp := person.NewLineParser(d, formats.forSource(file.Name))
+			for _, record := range filesRecords[i] {
+				p, isHeader, semParseErrs := lp.ParseRecord(record.Fields)
 				if len(semParseErrs) > 0 {
-					parseErrs = append(parseErrs, prependFileInfo(file.Name, j+1, semParseErrs)...)
+					parseErrs = append(parseErrs, prependFileInfo(file.Name, record.Line, semParseErrs)...)
+					continue
+				}
+				if isHeader {
 					continue
 				}
 				persons = append(persons, p)
@@ -127,35 +137,186 @@ func (s sortStyle) String() string {
 	return s.str
 }
 
+// Set accepts one of the named sort styles or, failing that, a sort
+// specification like "gender,last_name,-birthdate".
 func (s *sortStyle) Set(str string) error {
 	sortFn, ok := sortStyleToSortFn[str]
 	if !ok {
-		possibleSortStyles := []string{}
-		for key := range sortStyleToSortFn {
-			possibleSortStyles = append(possibleSortStyles, key)
+		spec, err := sortspec.Parse(str)
+		if err != nil {
+			possibleSortStyles := []string{}
+			for key := range sortStyleToSortFn {
+				possibleSortStyles = append(possibleSortStyles, key)
+			}
+			sort.Strings(possibleSortStyles)
+			return fmt.Errorf("invalid value, allowed values are %s or a sort specification like \"gender,last_name,-birthdate\" (%v)", strings.Join(possibleSortStyles, ", "), err)
 		}
-		sort.Strings(possibleSortStyles)
-		return fmt.Errorf("invalid value, allowed values are %s", strings.Join(possibleSortStyles, ", "))
+		sortFn = spec.Sort
 	}
 	s.str = str
 	s.fn = sortFn
 	return nil
 }
 
+// upcomingDays is the number of days to look ahead for birthdays.
+// Until it is set the command line tool does not look for birthdays.
+type upcomingDays struct {
+	set  bool
+	days int
+}
+
+func (u upcomingDays) String() string {
+	if !u.set {
+		return ""
+	}
+	return strconv.Itoa(u.days)
+}
+
+func (u *upcomingDays) Set(str string) error {
+	days, err := strconv.Atoi(str)
+	if err != nil || days < 0 {
+		return errors.New("must be a non-negative integer")
+	}
+	u.set = true
+	u.days = days
+	return nil
+}
+
+// dateFormats are the formats a date of birth can be in. They can be
+// given for every source or, like "old.txt=D/M/YYYY", for just one.
+// Sources are compared after filepath.Clean so "./old.txt" is the
+// same file as "old.txt".
+type dateFormats struct {
+	all      []string
+	bySource map[string][]string
+}
+
+func (f dateFormats) String() string {
+	return strings.Join(f.all, ",")
+}
+
+func (f *dateFormats) Set(str string) error {
+	source := ""
+	if i := strings.LastIndex(str, "="); i != -1 {
+		source, str = str[:i], str[i+1:]
+	}
+	formats, err := person.ParseDateFormats(str)
+	if err != nil {
+		return err
+	}
+	if source == "" {
+		f.all = formats
+		return nil
+	}
+	if f.bySource == nil {
+		f.bySource = map[string][]string{}
+	}
+	f.bySource[filepath.Clean(source)] = formats
+	return nil
+}
+
+func (f dateFormats) forSource(name string) []string {
+	if formats, ok := f.bySource[filepath.Clean(name)]; ok {
+		return formats
+	}
+	return f.all
+}
+
+// unknownSources returns, sorted, the sources formats were given for
+// which are not among the file names.
+func (f dateFormats) unknownSources(fileNames []string) []string {
+	known := map[string]bool{}
+	for _, name := range fileNames {
+		known[filepath.Clean(name)] = true
+	}
+	unknown := []string{}
+	for source := range f.bySource {
+		if !known[source] {
+			unknown = append(unknown, source)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// whereErrMsg explains what is wrong with a -where expression by
+// pointing at the offending column.
+func whereErrMsg(expr string, err error) string {
+	msg := fmt.Sprintf("invalid -where expression: %v", err)
+	if parseErr, ok := err.(*where.ParseError); ok {
+		msg += fmt.Sprintf("\n    %s\n    %s^", expr, strings.Repeat(" ", parseErr.Col-1))
+	}
+	return msg
+}
+
 func main() {
 	var ss = sortStyle{str: defaultSort, fn: sortStyleToSortFn[defaultSort]}
+	var upcoming upcomingDays
+	var formats = dateFormats{all: person.DefaultDateFormats}
 	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
-	fs.Var(&ss, "sort", "how to sort the data")
+	fs.Var(&ss, "sort", fmt.Sprintf("how to sort the data, either a named style or a comma separated list of fields (%s) each optionally prefixed with - to sort descending", strings.Join(sortspec.FieldNames(), ", ")))
+	whereStr := fs.String("where", "", fmt.Sprintf("only output the records matching an expression like 'gender == \"Female\" && birthdate < 1990-01-01 || color in (\"red\", \"blue\")' where the fields are %s", strings.Join(where.FieldNames(), ", ")))
+	dialectPath := fs.String("dialect", "", "JSON file describing the format of the input, see multicsv.LoadDialect, if empty pipe, comma and space separated records are accepted")
+	fs.Var(&formats, "date-formats", fmt.Sprintf("comma separated list of the formats, out of %s, a date of birth can be in, prefix it with a file name and = to only use it for that file, can be given more than once", strings.Join(person.DateFormatNames(), ", ")))
+	fieldsStr := fs.String("fields", "", fmt.Sprintf("comma separated list of the fields to output and their order, out of %s", strings.Join(fields.Person, ", ")))
+	fs.Var(&upcoming, "upcoming-birthdays", "only output the records whose birthday is within this many days (0 means today), soonest birthday first instead of sorted")
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		os.Exit(2)
 	}
-	persons, errs := parseDataFromFiles(fs.Args())
+	var fieldNames []string
+	if *fieldsStr != "" {
+		var err error
+		fieldNames, err = fields.Parse(*fieldsStr, fields.Person)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -fields: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var whereExpr *where.Expr
+	if *whereStr != "" {
+		e, err := where.Parse(*whereStr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, whereErrMsg(*whereStr, err))
+			os.Exit(2)
+		}
+		whereExpr = &e
+	}
+	dialect := multicsv.DefaultDialect
+	if *dialectPath != "" {
+		var err error
+		if dialect, err = multicsv.LoadDialect(*dialectPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if dialect.NumFields != person.NumFields {
+			fmt.Fprintf(os.Stderr, "invalid dialect %s: records have %d fields, not %d\n", *dialectPath, person.NumFields, dialect.NumFields)
+			os.Exit(2)
+		}
+	}
+	if unknown := formats.unknownSources(fs.Args()); len(unknown) > 0 {
+		for _, source := range unknown {
+			fmt.Fprintf(os.Stderr, "invalid -date-formats: %s is not one of the files to read\n", source)
+		}
+		os.Exit(2)
+	}
+	persons, errs := parseDataFromFiles(fs.Args(), dialect, formats)
 	if len(errs) > 0 {
 		fmt.Fprintln(os.Stderr, strings.Join(errs, "\n"))
 		os.Exit(1)
 	}
-	ss.fn(persons)
+	if whereExpr != nil {
+		persons = whereExpr.Apply(persons)
+	}
+	if upcoming.set {
+		persons = birthdays.Upcoming(persons, time.Now(), upcoming.days)
+	} else {
+		ss.fn(persons)
+	}
 	for _, p := range persons {
+		if fieldNames != nil {
+			fmt.Println(fields.Marshal(p, fieldNames))
+			continue
+		}
 		fmt.Println(person.Marshal(p))
 	}
 }
@@ -1,3 +1,4 @@
+//go:build e2e
 // +build e2e
 
 package e2e_test
@@ -78,17 +79,79 @@ func TestPostFails(t *testing.T) {
 	// if they vary I'm not sure it should matter. Testing that an
 	// error message exists seems important but I don't think we
 	// have to care overly much about the specific contents.
-	if got, want := string(body), `{"errors":["there should only be one type of separator but multiple (',', ' ') were specified"]}`; got != want {
+	if got, want := string(body), `{"results":[{"line":1,"errors":["there should only be one type of separator but multiple (',', ' ') were specified"]}],"errors":["1: there should only be one type of separator but multiple (',', ' ') were specified"]}`; got != want {
 		t.Errorf("when posting invalid data got body %s, want %s", got, want)
 	}
 }
 
+func TestBulkPost(t *testing.T) {
+	body := `Took,Peregrin,Male,Yellow,1932-06-09
+not a valid record
+Brandybuck|Meriadoc|Male|Green|1914-08-12`
+	resp := sendRequest(newRequest(http.MethodPost, "/records", strings.NewReader(body)))
+	if got, want := resp.StatusCode, http.StatusMultiStatus; got != want {
+		t.Errorf("when posting some invalid records got status code %d, want %d", got, want)
+	}
+	var data struct {
+		Results []struct {
+			Line   int      `json:"line"`
+			ID     int      `json:"id"`
+			Errors []string `json:"errors"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(readAll(resp.Body), &data); err != nil {
+		panic(err)
+	}
+	if got, want := len(data.Results), 3; got != want {
+		t.Fatalf("got %d results, want %d", got, want)
+	}
+	for i, result := range data.Results {
+		if got, want := result.Line, i+1; got != want {
+			t.Errorf("got line %d, want %d", got, want)
+		}
+		if got, want := result.ID == 0, i == 1; got != want {
+			t.Errorf("for line %d got result %+v", i+1, result)
+		}
+	}
+}
+
 type apiResp struct {
 	Data []struct {
+		ID       int    `json:"id"`
 		LastName string `json:"last_name"`
 	} `json:"data"`
 }
 
+func TestGetRecordByID(t *testing.T) {
+	resp := sendRequest(newRequest(http.MethodPost, "/records", strings.NewReader("Baggins,Bilbo,Male,Green,1890-09-22")))
+	if got, want := resp.StatusCode, http.StatusCreated; got != want {
+		t.Fatalf("when posting a record got status code %d, want %d", got, want)
+	}
+	var created apiResp
+	if err := json.Unmarshal(readAll(resp.Body), &created); err != nil {
+		panic(err)
+	}
+	location := resp.Header.Get("Location")
+	if got, want := location, fmt.Sprintf("/records/%d", created.Data[0].ID); got != want {
+		t.Errorf("got Location header %q, want %q", got, want)
+	}
+	resp = sendRequest(newRequest(http.MethodGet, location, nil))
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Errorf("when getting the created record got status code %d, want %d", got, want)
+	}
+	var fetched apiResp
+	if err := json.Unmarshal(readAll(resp.Body), &fetched); err != nil {
+		panic(err)
+	}
+	if got, want := fetched.Data[0].LastName, "Baggins"; got != want {
+		t.Errorf("got last name %q, want %q", got, want)
+	}
+	resp = sendRequest(newRequest(http.MethodGet, "/records/999999", nil))
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("when getting a missing record got status code %d, want %d", got, want)
+	}
+}
+
 func TestAPIGetSortsSucceed(t *testing.T) {
 	records := []string{
 		"Avatar,Aang,Male,Light-Orange,1760-12-13",
@@ -101,7 +164,7 @@ func TestAPIGetSortsSucceed(t *testing.T) {
 	}
 	for _, record := range records {
 		resp := sendRequest(newRequest(http.MethodPost, "/records", strings.NewReader(record)))
-		if got, want := resp.StatusCode, http.StatusOK; got != want {
+		if got, want := resp.StatusCode, http.StatusCreated; got != want {
 			t.Errorf("when posting record %q got status code %d, want %d", record, got, want)
 		}
 	}
@@ -130,3 +193,28 @@ func TestAPIGetSortsSucceed(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceAndDeleteRecord(t *testing.T) {
+	resp := sendRequest(newRequest(http.MethodPost, "/records", strings.NewReader("Gamgee,Samwise,Mail,Brown,1890-04-06")))
+	if got, want := resp.StatusCode, http.StatusCreated; got != want {
+		t.Fatalf("when posting a record got status code %d, want %d", got, want)
+	}
+	location := resp.Header.Get("Location")
+	resp = sendRequest(newRequest(http.MethodPut, location, strings.NewReader("Gamgee|Samwise|Male|Brown|1890-04-06")))
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Errorf("when replacing a record got status code %d, want %d", got, want)
+	}
+	resp = sendRequest(newRequest(http.MethodGet, location, nil))
+	body := readAll(resp.Body)
+	if !strings.Contains(string(body), `"gender":"Male"`) {
+		t.Errorf("expected the record to be replaced, got body %s", body)
+	}
+	resp = sendRequest(newRequest(http.MethodDelete, location, nil))
+	if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+		t.Errorf("when deleting a record got status code %d, want %d", got, want)
+	}
+	resp = sendRequest(newRequest(http.MethodGet, location, nil))
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("when getting a deleted record got status code %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+// Package birthdays answers questions about when people's birthdays
+// are.
+package birthdays
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+)
+
+// DaysUntil returns how many days after the date from the next
+// birthday of someone born on dob is. It is 0 if their birthday is on
+// that date.
+func DaysUntil(dob time.Time, from time.Time) int {
+	year, month, day := from.Date()
+	on := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return int(person.NextBirthday(dob, on).Sub(on).Hours() / 24)
+}
+
+// Upcoming returns the people whose next birthday is at most days
+// days after the date from, soonest first. People with birthdays on
+// the same day are kept in their original order.
+func Upcoming(ps []person.Person, from time.Time, days int) []person.Person {
+	type upcoming struct {
+		p         person.Person
+		daysUntil int
+	}
+	found := []upcoming{}
+	for _, p := range ps {
+		if n := DaysUntil(p.DateOfBirth, from); n <= days {
+			found = append(found, upcoming{p, n})
+		}
+	}
+	sort.SliceStable(found, func(i int, j int) bool {
+		return found[i].daysUntil < found[j].daysUntil
+	})
+	upcomingPs := make([]person.Person, len(found))
+	for i, u := range found {
+		upcomingPs[i] = u.p
+	}
+	return upcomingPs
+}
@@ -0,0 +1,59 @@
+package birthdays_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/birthdays"
+	"github.com/lag13/records/internal/person"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDaysUntil(t *testing.T) {
+	tests := []struct {
+		name     string
+		dob      time.Time
+		from     time.Time
+		wantDays int
+	}{
+		{name: "birthday today", dob: date(1990, time.May, 15), from: date(2020, time.May, 15), wantDays: 0},
+		{name: "birthday tomorrow", dob: date(1990, time.May, 15), from: date(2020, time.May, 14), wantDays: 1},
+		{name: "birthday yesterday", dob: date(1990, time.May, 15), from: date(2020, time.May, 16), wantDays: 364},
+		{name: "birthday next year", dob: date(1990, time.January, 5), from: date(2020, time.December, 30), wantDays: 6},
+		{name: "leap day birthday is on March 1 in a common year", dob: date(2000, time.February, 29), from: date(2021, time.February, 28), wantDays: 1},
+		{name: "leap day birthday in a leap year", dob: date(2000, time.February, 29), from: date(2024, time.February, 28), wantDays: 1},
+		{name: "leap day birthday just passed", dob: date(2000, time.February, 29), from: date(2023, time.March, 2), wantDays: 364},
+		{name: "March 1 birthday in a leap year", dob: date(1990, time.March, 1), from: date(2024, time.February, 28), wantDays: 2},
+		{name: "the day is the one in the from time zone", dob: date(1990, time.May, 15), from: time.Date(2020, time.May, 14, 23, 30, 0, 0, time.FixedZone("", 3*60*60)), wantDays: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := birthdays.DaysUntil(test.dob, test.from); got != test.wantDays {
+				t.Errorf("got %d days, want %d", got, test.wantDays)
+			}
+		})
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	ps := []person.Person{
+		{ID: 1, DateOfBirth: date(1990, time.February, 1)},
+		{ID: 2, DateOfBirth: date(1985, time.January, 2)},
+		{ID: 3, DateOfBirth: date(2000, time.December, 25)},
+		{ID: 4, DateOfBirth: date(1970, time.December, 31)},
+		{ID: 5, DateOfBirth: date(1999, time.January, 2)},
+	}
+	got := birthdays.Upcoming(ps, date(2020, time.December, 26), 10)
+	want := []person.Person{
+		{ID: 4, DateOfBirth: date(1970, time.December, 31)},
+		{ID: 2, DateOfBirth: date(1985, time.January, 2)},
+		{ID: 5, DateOfBirth: date(1999, time.January, 2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
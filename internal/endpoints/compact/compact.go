@@ -0,0 +1,33 @@
+// Package compact defines a handler which lets an administrator force
+// the on disk records to be compacted.
+package compact
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lag13/records/internal/response"
+)
+
+// Compactor is something whose on disk representation can be
+// compacted.
+type Compactor interface {
+	Compact() error
+}
+
+// Compact compacts the given Compactor.
+func Compact(req *http.Request, c Compactor) (response.Structured, error) {
+	if req.Method != http.MethodPost {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     []string{fmt.Sprintf("this endpoint works with a POST request, not a %s", req.Method)},
+		}, nil
+	}
+	if err := c.Compact(); err != nil {
+		return response.Structured{
+			StatusCode: http.StatusInternalServerError,
+			Errors:     []string{"unexpected error"},
+		}, err
+	}
+	return response.Structured{StatusCode: http.StatusOK}, nil
+}
@@ -0,0 +1,83 @@
+package compact_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/lag13/records/internal/endpoints/compact"
+	"github.com/lag13/records/internal/response"
+)
+
+func errToStr(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprint(err)
+}
+
+type mockCompactor struct {
+	err    error
+	called bool
+}
+
+func (m *mockCompactor) Compact() error {
+	m.called = true
+	return m.err
+}
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *http.Request
+		compactor  *mockCompactor
+		wantCalled bool
+		wantResp   response.Structured
+		errMsg     string
+	}{
+		{
+			name:      "invalid http method",
+			req:       httptest.NewRequest("GET", "/asdf", nil),
+			compactor: &mockCompactor{},
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"this endpoint works with a POST request, not a GET"},
+			},
+		},
+		{
+			name:       "compacting fails",
+			req:        httptest.NewRequest("POST", "/asdf", nil),
+			compactor:  &mockCompactor{err: errors.New("disk full")},
+			wantCalled: true,
+			wantResp: response.Structured{
+				StatusCode: 500,
+				Errors:     []string{"unexpected error"},
+			},
+			errMsg: "disk full",
+		},
+		{
+			name:       "success",
+			req:        httptest.NewRequest("POST", "/asdf", nil),
+			compactor:  &mockCompactor{},
+			wantCalled: true,
+			wantResp:   response.Structured{StatusCode: 200},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := compact.Compact(test.req, test.compactor)
+			if got, want := errToStr(err), test.errMsg; got != want {
+				t.Errorf("got error %q, want %q", got, want)
+			}
+			if got, want := resp, test.wantResp; !reflect.DeepEqual(got, want) {
+				t.Errorf("got resp %+v, want %+v", got, want)
+			}
+			if got, want := test.compactor.called, test.wantCalled; got != want {
+				t.Errorf("got compactor called %t, want %t", got, want)
+			}
+		})
+	}
+}
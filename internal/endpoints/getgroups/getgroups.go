@@ -0,0 +1,102 @@
+// Package getgroups defines a handler which returns person data
+// grouped by the value of one of their fields.
+package getgroups
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lag13/records/internal/fields"
+	"github.com/lag13/records/internal/filter"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+	"github.com/lag13/records/internal/sortspec"
+)
+
+const (
+	// ByParam is the query parameter naming the field to group by.
+	ByParam = "by"
+	// SortParam is the query parameter holding the sort
+	// specification (see the sortspec package) used to sort the
+	// records within each group.
+	SortParam = "sort"
+)
+
+// groupKeys are the fields which can be grouped by. Text is grouped
+// without regard to case so those keys are lower case.
+var groupKeys = map[string]func(p person.Person) string{
+	"gender": func(p person.Person) string {
+		return strings.ToLower(p.Gender)
+	},
+	"favorite_color": func(p person.Person) string {
+		return strings.ToLower(p.FavoriteColor)
+	},
+	"birth_year": func(p person.Person) string {
+		return strconv.Itoa(p.DateOfBirth.Year())
+	},
+}
+
+func groupNames() []string {
+	names := []string{}
+	for name := range groupKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Groups returns the given people grouped by the field named in the
+// "by" query parameter. The people in each group are sorted according
+// to the "sort" query parameter, by id if there is none. The people
+// can first be filtered using the query parameters understood by the
+// filter package.
+func Groups(req *http.Request, ps []person.Person) response.Structured {
+	if req.Method != http.MethodGet {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     []string{fmt.Sprintf("this endpoint works with a GET request, not a %s", req.Method)},
+		}
+	}
+	query := req.URL.Query()
+	errs := []string{}
+	groupKey, ok := groupKeys[query.Get(ByParam)]
+	if !ok {
+		errs = append(errs, fmt.Sprintf("%s must be one of %s", ByParam, strings.Join(groupNames(), ", ")))
+	}
+	specStr := query.Get(SortParam)
+	if specStr == "" {
+		specStr = "id"
+	}
+	spec, err := sortspec.Parse(specStr)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("%s: %v", SortParam, err))
+	}
+	fieldNames, fieldErrs := fields.FromQuery(query)
+	errs = append(errs, fieldErrs...)
+	f, filterErrs := filter.Parse(query, ByParam, SortParam, fields.Param)
+	errs = append(errs, filterErrs...)
+	if len(errs) > 0 {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     errs,
+		}
+	}
+	// Sorting before grouping keeps each group sorted since
+	// grouping preserves the order of the records.
+	tmp := f.Apply(ps)
+	spec.Sort(tmp)
+	groups := map[string][]person.Person{}
+	for _, p := range tmp {
+		key := groupKey(p)
+		groups[key] = append(groups[key], p)
+	}
+	return response.Structured{
+		StatusCode: http.StatusOK,
+		AsOf:       f.AsOf,
+		Fields:     fieldNames,
+		Groups:     groups,
+	}
+}
@@ -0,0 +1,112 @@
+package getgroups_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/endpoints/getgroups"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+func TestGroups(t *testing.T) {
+	date := func(year int) time.Time {
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	bilbo := person.Person{ID: 1, LastName: "Baggins", FirstName: "Bilbo", Gender: "Male", FavoriteColor: "green", DateOfBirth: date(1890)}
+	lobelia := person.Person{ID: 2, LastName: "Sackville-Baggins", FirstName: "Lobelia", Gender: "female", FavoriteColor: "Green", DateOfBirth: date(1900)}
+	frodo := person.Person{ID: 3, LastName: "Baggins", FirstName: "Frodo", Gender: "male", FavoriteColor: "blue", DateOfBirth: date(1968)}
+	sam := person.Person{ID: 4, LastName: "Gamgee", FirstName: "Samwise", Gender: "male", FavoriteColor: "brown", DateOfBirth: date(1968)}
+	ps := []person.Person{bilbo, lobelia, frodo, sam}
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantResp response.Structured
+	}{
+		{
+			name: "wrong http method",
+			req:  httptest.NewRequest("POST", "/records/groups?by=gender", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"this endpoint works with a GET request, not a POST"},
+			},
+		},
+		{
+			name: "invalid query parameters",
+			req:  httptest.NewRequest("GET", "/records/groups?by=age&sort=height&shoe_size=9", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors: []string{
+					"by must be one of birth_year, favorite_color, gender",
+					`sort: unknown sort field "height", allowed fields are id, last_name, first_name, gender, favorite_color, birthdate`,
+					`unknown query parameter "shoe_size"`,
+				},
+			},
+		},
+		{
+			name: "group by gender sorted by id",
+			req:  httptest.NewRequest("GET", "/records/groups?by=gender", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Groups: map[string][]person.Person{
+					"female": {lobelia},
+					"male":   {bilbo, frodo, sam},
+				},
+			},
+		},
+		{
+			name: "group by favorite color",
+			req:  httptest.NewRequest("GET", "/records/groups?by=favorite_color&sort=-birthdate", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Groups: map[string][]person.Person{
+					"green": {lobelia, bilbo},
+					"blue":  {frodo},
+					"brown": {sam},
+				},
+			},
+		},
+		{
+			name: "group by birth year",
+			req:  httptest.NewRequest("GET", "/records/groups?by=birth_year&sort=-firstname", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Groups: map[string][]person.Person{
+					"1890": {bilbo},
+					"1900": {lobelia},
+					"1968": {sam, frodo},
+				},
+			},
+		},
+		{
+			name: "filtered",
+			req:  httptest.NewRequest("GET", "/records/groups?by=birth_year&last_name_prefix=bag", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Groups: map[string][]person.Person{
+					"1890": {bilbo},
+					"1968": {frodo},
+				},
+			},
+		},
+		{
+			name: "nothing matches",
+			req:  httptest.NewRequest("GET", "/records/groups?by=gender&last_name_prefix=took", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Groups:     map[string][]person.Person{},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotResp := getgroups.Groups(test.req, ps)
+			if !reflect.DeepEqual(gotResp, test.wantResp) {
+				t.Errorf("got response %+v, want %+v", gotResp, test.wantResp)
+			}
+		})
+	}
+}
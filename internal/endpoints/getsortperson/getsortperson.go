@@ -6,24 +6,102 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/lag13/records/internal/fields"
+	"github.com/lag13/records/internal/filter"
+	"github.com/lag13/records/internal/paginate"
 	"github.com/lag13/records/internal/person"
 	"github.com/lag13/records/internal/response"
+	"github.com/lag13/records/internal/sortspec"
 )
 
 // Sort will return a response containing the given list of people
-// sorted according to the given sorting function.
+// sorted according to the given sorting function. The people can be
+// filtered using the query parameters understood by the filter
+// package. If the request asks for a page (using the limit, offset or
+// cursor query parameters) then only that page is returned.
 func Sort(req *http.Request, sortFn func(ps []person.Person), ps []person.Person) response.Structured {
+	return List(req, ps, sorted(sortFn), nil)
+}
+
+// sorted returns an order for List which sorts the people with sortFn.
+func sorted(sortFn func(ps []person.Person)) func(ps []person.Person) []person.Person {
+	return func(ps []person.Person) []person.Person {
+		sortFn(ps)
+		return ps
+	}
+}
+
+// SortParam is the query parameter which holds the sort specification
+// for SortBySpec.
+const SortParam = "sort"
+
+// SortBySpec is like Sort except the people are sorted according to
+// the sort specification (see the sortspec package) in the "sort"
+// query parameter. Without one the people are sorted by id.
+func SortBySpec(req *http.Request, ps []person.Person) response.Structured {
+	specStr := req.URL.Query().Get(SortParam)
+	if specStr == "" {
+		specStr = "id"
+	}
+	var errs []string
+	spec, err := sortspec.Parse(specStr)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("%s: %v", SortParam, err))
+	}
+	return List(req, ps, sorted(spec.Sort), errs, SortParam)
+}
+
+// List returns the people, filtered and paginated like Sort, in the
+// order given by order which is passed the filtered people and can
+// modify them. otherParams are query parameters, besides the
+// filtering, pagination and fields ones, that the caller understands
+// and errs are the problems the caller found with them, which are
+// reported along with any others.
+func List(req *http.Request, ps []person.Person, order func(ps []person.Person) []person.Person, errs []string, otherParams ...string) response.Structured {
 	if req.Method != http.MethodGet {
 		return response.Structured{
 			StatusCode: http.StatusBadRequest,
 			Errors:     []string{fmt.Sprintf("this endpoint works with a GET request, not a %s", req.Method)},
 		}
 	}
-	tmp := make([]person.Person, len(ps))
-	copy(tmp, ps)
-	sortFn(tmp)
+	query := req.URL.Query()
+	pageReq, pageErrs := paginate.Parse(query)
+	errs = append(errs, pageErrs...)
+	fieldNames, fieldErrs := fields.FromQuery(query)
+	errs = append(errs, fieldErrs...)
+	otherParams = append(otherParams, paginate.LimitParam, paginate.OffsetParam, paginate.CursorParam, fields.Param)
+	f, filterErrs := filter.Parse(query, otherParams...)
+	errs = append(errs, filterErrs...)
+	if len(errs) > 0 {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     errs,
+		}
+	}
+	// Filtering first means there is less to order and, since
+	// Apply returns a new slice, the caller's slice is not
+	// modified by ordering.
+	tmp := order(f.Apply(ps))
+	if !paginate.Requested(query) {
+		return response.Structured{
+			StatusCode: http.StatusOK,
+			AsOf:       f.AsOf,
+			Fields:     fieldNames,
+			Data:       tmp,
+		}
+	}
+	page, pagination, errs := paginate.Page(req.URL, tmp, pageReq)
+	if len(errs) > 0 {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     errs,
+		}
+	}
 	return response.Structured{
 		StatusCode: http.StatusOK,
-		Data:       tmp,
+		AsOf:       f.AsOf,
+		Fields:     fieldNames,
+		Data:       page,
+		Pagination: pagination,
 	}
 }
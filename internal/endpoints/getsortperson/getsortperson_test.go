@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/lag13/records/internal/endpoints/getsortperson"
 	"github.com/lag13/records/internal/person"
@@ -47,6 +48,108 @@ func TestSort(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:   "invalid pagination",
+			req:    httptest.NewRequest("GET", "/asdf?limit=0", nil),
+			sortFn: nil,
+			ps:     nil,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"limit must be an integer between 1 and 1000"},
+			},
+		},
+		{
+			name:   "invalid filter",
+			req:    httptest.NewRequest("GET", "/asdf?born_after=yesterday&limit=5", nil),
+			sortFn: nil,
+			ps:     nil,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"born_after must have the format YYYY-MM-DD"},
+			},
+		},
+		{
+			name:   "filter before sorting",
+			req:    httptest.NewRequest("GET", "/asdf?last_name_prefix=b", nil),
+			sortFn: func(ps []person.Person) {},
+			ps: []person.Person{
+				{LastName: "Bobbo"},
+				{LastName: "Vincent"},
+				{LastName: "Baggins"},
+			},
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data: []person.Person{
+					{LastName: "Bobbo"},
+					{LastName: "Baggins"},
+				},
+			},
+		},
+		{
+			name:   "filter by age",
+			req:    httptest.NewRequest("GET", "/asdf?max_age=18&as_of=2020-06-01", nil),
+			sortFn: func(ps []person.Person) {},
+			ps: []person.Person{
+				{LastName: "Bobbo", DateOfBirth: time.Date(2002, time.June, 1, 0, 0, 0, 0, time.UTC)},
+				{LastName: "Vincent", DateOfBirth: time.Date(2001, time.June, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			wantResp: response.Structured{
+				StatusCode: 200,
+				AsOf:       time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC),
+				Data: []person.Person{
+					{LastName: "Bobbo", DateOfBirth: time.Date(2002, time.June, 1, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+		},
+		{
+			name:   "invalid fields",
+			req:    httptest.NewRequest("GET", "/asdf?fields=last_name,,gender", nil),
+			sortFn: nil,
+			ps:     nil,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"fields: field 2 is empty"},
+			},
+		},
+		{
+			name:   "only some fields",
+			req:    httptest.NewRequest("GET", "/asdf?fields=Birthdate,last_name", nil),
+			sortFn: func(ps []person.Person) {},
+			ps: []person.Person{
+				{LastName: "Bobbo"},
+			},
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Fields:     []string{"birthdate", "last_name"},
+				Data: []person.Person{
+					{LastName: "Bobbo"},
+				},
+			},
+		},
+		{
+			name: "get a page",
+			req:  httptest.NewRequest("GET", "/asdf?limit=1&offset=1", nil),
+			sortFn: func(ps []person.Person) {
+				ps[0], ps[2] = ps[2], ps[0]
+			},
+			ps: []person.Person{
+				{LastName: "Bobbo"},
+				{LastName: "Vincent"},
+				{LastName: "Zed"},
+			},
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data: []person.Person{
+					{LastName: "Vincent"},
+				},
+				Pagination: &response.Pagination{
+					Total:  3,
+					Limit:  1,
+					Offset: 1,
+					Next:   "/asdf?limit=1&offset=2",
+				},
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -57,3 +160,57 @@ func TestSort(t *testing.T) {
 		})
 	}
 }
+
+func TestSortBySpec(t *testing.T) {
+	ps := []person.Person{
+		{ID: 2, LastName: "Bobbo", Gender: "Male"},
+		{ID: 1, LastName: "Vincent", Gender: "Female"},
+		{ID: 3, LastName: "Adams", Gender: "Male"},
+	}
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantResp response.Structured
+	}{
+		{
+			name: "wrong http method",
+			req:  httptest.NewRequest("POST", "/asdf", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"this endpoint works with a GET request, not a POST"},
+			},
+		},
+		{
+			name: "invalid sort spec",
+			req:  httptest.NewRequest("GET", "/asdf?sort=gender,height", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{`sort: unknown sort field "height", allowed fields are id, last_name, first_name, gender, favorite_color, birthdate`},
+			},
+		},
+		{
+			name: "sorted by id by default",
+			req:  httptest.NewRequest("GET", "/asdf", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{ps[1], ps[0], ps[2]},
+			},
+		},
+		{
+			name: "sorted by the spec and filtered",
+			req:  httptest.NewRequest("GET", "/asdf?sort=-gender,lastname&gender=male", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{ps[2], ps[0]},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := getsortperson.SortBySpec(test.req, ps)
+			if got, want := resp, test.wantResp; !reflect.DeepEqual(got, want) {
+				t.Errorf("got response %+v, want %+v", got, want)
+			}
+		})
+	}
+}
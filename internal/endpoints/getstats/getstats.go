@@ -0,0 +1,43 @@
+// Package getstats defines a handler which returns summary statistics
+// about person data.
+package getstats
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lag13/records/internal/filter"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+	"github.com/lag13/records/internal/stats"
+)
+
+// Stats returns statistics about the given people (see the stats
+// package) with ages calculated as of now, unless the as_of query
+// parameter says otherwise. The people can first be
+// filtered using the query parameters understood by the filter
+// package.
+func Stats(req *http.Request, ps []person.Person, now time.Time) response.Structured {
+	if req.Method != http.MethodGet {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     []string{fmt.Sprintf("this endpoint works with a GET request, not a %s", req.Method)},
+		}
+	}
+	f, errs := filter.Parse(req.URL.Query())
+	if len(errs) > 0 {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     errs,
+		}
+	}
+	if !f.AsOf.IsZero() {
+		now = f.AsOf
+	}
+	s := stats.Compute(f.Apply(ps), now)
+	return response.Structured{
+		StatusCode: http.StatusOK,
+		Stats:      &s,
+	}
+}
@@ -0,0 +1,87 @@
+package getstats_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/endpoints/getstats"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+	"github.com/lag13/records/internal/stats"
+)
+
+func TestStats(t *testing.T) {
+	dob := time.Date(1990, time.March, 10, 0, 0, 0, 0, time.UTC)
+	medianAge := 30.0
+	medianAgeIn2010 := 19.0
+	ps := []person.Person{
+		{ID: 1, Gender: "female", FavoriteColor: "red", DateOfBirth: dob},
+		{ID: 2, Gender: "male", FavoriteColor: "red", DateOfBirth: dob},
+	}
+	now := time.Date(2020, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantResp response.Structured
+	}{
+		{
+			name: "wrong http method",
+			req:  httptest.NewRequest("POST", "/records/stats", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"this endpoint works with a GET request, not a POST"},
+			},
+		},
+		{
+			name: "invalid filter",
+			req:  httptest.NewRequest("GET", "/records/stats?born_after=yesterday", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"born_after must have the format YYYY-MM-DD"},
+			},
+		},
+		{
+			name: "filtered stats",
+			req:  httptest.NewRequest("GET", "/records/stats?gender=female", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Stats: &stats.Summary{
+					Count:             1,
+					ByGender:          map[string]int{"female": 1},
+					ByFavoriteColor:   map[string]int{"red": 1},
+					OldestBirthdate:   &dob,
+					YoungestBirthdate: &dob,
+					MedianAge:         &medianAge,
+					ByBirthDecade:     map[string]int{"1990s": 1},
+				},
+			},
+		},
+		{
+			name: "ages as of another date",
+			req:  httptest.NewRequest("GET", "/records/stats?as_of=2010-03-09&gender=male", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Stats: &stats.Summary{
+					Count:             1,
+					ByGender:          map[string]int{"male": 1},
+					ByFavoriteColor:   map[string]int{"red": 1},
+					OldestBirthdate:   &dob,
+					YoungestBirthdate: &dob,
+					MedianAge:         &medianAgeIn2010,
+					ByBirthDecade:     map[string]int{"1990s": 1},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotResp := getstats.Stats(test.req, ps, now)
+			if !reflect.DeepEqual(gotResp, test.wantResp) {
+				t.Errorf("got response %+v, want %+v", gotResp, test.wantResp)
+			}
+		})
+	}
+}
@@ -1,64 +1,161 @@
-// Package postrecord parses an POST request which will create a
-// record.
+// Package postrecord parses an POST request which will create
+// records.
 package postrecord
 
 import (
 	"bufio"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 
+	"github.com/lag13/records/internal/endpoints/recordbyid"
 	"github.com/lag13/records/internal/multicsv"
 	"github.com/lag13/records/internal/person"
 	"github.com/lag13/records/internal/response"
 )
 
-// PostRecord parses the incoming request into a person which can then
-// be added to the database.
-func PostRecord(req *http.Request) (person.Person, response.Structured, error) {
+// Adder stores every person, or none of them if something goes wrong,
+// and returns the ids they were given.
+type Adder interface {
+	AddAll(ps []person.Person) ([]int, error)
+}
+
+// The modes a request can be processed in. They are selected with the
+// "mode" query parameter.
+const (
+	// ModeBestEffort adds every line which parsed successfully.
+	ModeBestEffort = "best-effort"
+	// ModeAtomic adds nothing unless every line parsed
+	// successfully.
+	ModeAtomic = "atomic"
+)
+
+var unexpectedErr = response.Structured{
+	StatusCode: http.StatusInternalServerError,
+	Errors:     []string{"unexpected error"},
+}
+
+// PostRecord parses every line of the incoming request into a person
+// and adds them according to the requested mode. The lines can be in
+// any mix of the formats of the dialect and blank lines and comments
+// are ignored. A header line, like
+// "FirstName|LastName|Gender|DateOfBirth|FavoriteColor", gives the
+// order of the fields in the lines after it. The response says what
+// happened to each line. Dates of birth can be in any of the
+// dateFormats unless the date_formats query parameter gives others.
+func PostRecord(req *http.Request, a Adder, d multicsv.Dialect, dateFormats []string) (response.Structured, error) {
 	// TODO: There is repetition in this checking for the correct
 	// method and returning an error message if it is not the
 	// correct one. One solution would be to use a router which
 	// allows you to specify the method when registering the path.
 	if req.Method != http.MethodPost {
-		return person.Person{}, response.Structured{
+		return response.Structured{
 			StatusCode: http.StatusBadRequest,
 			Errors:     []string{fmt.Sprintf("this endpoint works with a POST request, not a %s", req.Method)},
 		}, nil
 	}
-	r := bufio.NewReader(req.Body)
-	line, err := r.ReadString('\n')
-	if err != nil && err != io.EOF {
-		// TODO: If I was being very good I would use
-		// pkg/errors to establish a stacktrace at this point
-		// in the code so when the error gets logged we know
-		// exactly where the failure happened.
-		return person.Person{}, response.Structured{
-			StatusCode: http.StatusInternalServerError,
-			Errors:     []string{"unexpected error"},
-		}, err
+	mode := req.URL.Query().Get("mode")
+	if mode == "" {
+		mode = ModeBestEffort
 	}
-	line = strings.TrimSpace(line)
+	if mode != ModeBestEffort && mode != ModeAtomic {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     []string{fmt.Sprintf("mode must be %s or %s, not %q", ModeBestEffort, ModeAtomic, mode)},
+		}, nil
+	}
+	dateFormats, errs := person.DateFormatsFromQuery(req.URL.Query(), dateFormats)
+	if len(errs) > 0 {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     errs,
+		}, nil
+	}
+	results := []response.LineResult{}
+	persons := []person.Person{}
+	scanner := bufio.NewScanner(req.Body)
+	lineNum := 0
 	// TODO: I don't like having code, which is unit tested,
 	// talking directly to other unit tested code from the same
-	// repository because it couples them. But perhaps I'll make
-	// an exception with the thought that *this* code, although
-	// unit tested, is not going to be consumed by anyone else
-	// (except main of course).
-	record, parseErr := multicsv.Parse(line, "|, ", 5)
-	if parseErr != "" {
-		return person.Person{}, response.Structured{
+	// repository because it couples them. But perhaps I'll make an
+	// exception with the thought that *this* code, although unit
+	// tested, is not going to be consumed by anyone else (except
+	// main of course).
+	lp := person.NewLineParser(d, dateFormats)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || d.IsComment(line) {
+			continue
+		}
+		p, isHeader, parseErrs := lp.ParseLine(line)
+		if isHeader && len(parseErrs) == 0 {
+			// A valid header is not a record so it is left
+			// out of the results.
+			continue
+		}
+		results = append(results, response.LineResult{Line: lineNum, Errors: parseErrs})
+		if len(parseErrs) == 0 && !isHeader {
+			persons = append(persons, p)
+		}
+	}
+	if err := scanner.Err(); err == bufio.ErrTooLong {
+		return response.Structured{
 			StatusCode: http.StatusBadRequest,
-			Errors:     []string{parseErr},
+			Errors:     []string{fmt.Sprintf("%d: the line is too long", lineNum+1)},
 		}, nil
+	} else if err != nil {
+		// TODO: If I was being very good I would use
+		// pkg/errors to establish a stacktrace at this point
+		// in the code so when the error gets logged we know
+		// exactly where the failure happened.
+		return unexpectedErr, err
 	}
-	p, parseErrs := person.Parse(record)
-	if len(parseErrs) > 0 {
-		return person.Person{}, response.Structured{
+	if len(results) == 0 {
+		return response.Structured{
 			StatusCode: http.StatusBadRequest,
-			Errors:     parseErrs,
+			Errors:     []string{"there were no records in the body"},
 		}, nil
 	}
-	return p, response.Structured{StatusCode: http.StatusOK}, nil
+	if mode == ModeAtomic && len(persons) < len(results) {
+		return summarize(results, nil), nil
+	}
+	ids, err := a.AddAll(persons)
+	if err != nil {
+		return unexpectedErr, err
+	}
+	for i, j := 0, 0; i < len(results); i++ {
+		if len(results[i].Errors) > 0 {
+			continue
+		}
+		persons[j].ID = ids[j]
+		results[i].ID = ids[j]
+		j++
+	}
+	return summarize(results, persons), nil
+}
+
+// summarize builds the response for a request whose lines have all
+// been dealt with.
+func summarize(results []response.LineResult, created []person.Person) response.Structured {
+	resp := response.Structured{
+		StatusCode: http.StatusCreated,
+		Data:       created,
+		Results:    results,
+	}
+	for _, result := range results {
+		for _, msg := range result.Errors {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("%d: %s", result.Line, msg))
+		}
+	}
+	switch {
+	case len(created) == 0:
+		resp.StatusCode = http.StatusBadRequest
+		resp.Data = nil
+	case len(resp.Errors) > 0:
+		resp.StatusCode = http.StatusMultiStatus
+	case len(created) == 1:
+		resp.Header = http.Header{"Location": []string{recordbyid.Location(created[0].ID)}}
+	}
+	return resp
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/lag13/records/internal/endpoints/postrecord"
+	"github.com/lag13/records/internal/multicsv"
 	"github.com/lag13/records/internal/person"
 	"github.com/lag13/records/internal/response"
 )
@@ -29,13 +30,51 @@ func (m mockErrReader) Read([]byte) (int, error) {
 	return 0, errors.New("non-nil error")
 }
 
+type mockAdder struct {
+	added []person.Person
+	err   error
+}
+
+func (m *mockAdder) AddAll(ps []person.Person) ([]int, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	ids := []int{}
+	for _, p := range ps {
+		m.added = append(m.added, p)
+		ids = append(ids, len(m.added)+10)
+	}
+	return ids, nil
+}
+
 func TestPostRecord(t *testing.T) {
+	gandalf := person.Person{
+		LastName:      "Grey",
+		FirstName:     "Gandalf",
+		Gender:        "Male",
+		FavoriteColor: "Rainbow",
+		DateOfBirth:   time.Date(1100, 4, 3, 0, 0, 0, 0, time.UTC),
+	}
+	gandalfWithID := gandalf
+	gandalfWithID.ID = 11
+	eowyn := person.Person{
+		LastName:      "Rohan",
+		FirstName:     "Eowyn",
+		Gender:        "Female",
+		FavoriteColor: "Gold",
+		DateOfBirth:   time.Date(1950, 7, 27, 0, 0, 0, 0, time.UTC),
+	}
+	eowynWithID := eowyn
+	eowynWithID.ID = 12
 	tests := []struct {
-		name       string
-		req        *http.Request
-		wantPerson person.Person
-		wantResp   response.Structured
-		errMsg     string
+		name        string
+		req         *http.Request
+		adder       mockAdder
+		dialect     multicsv.Dialect
+		dateFormats []string
+		wantAdded   []person.Person
+		wantResp    response.Structured
+		errMsg      string
 	}{
 		{
 			name: "invalid http method",
@@ -46,6 +85,15 @@ func TestPostRecord(t *testing.T) {
 			},
 			errMsg: "",
 		},
+		{
+			name: "invalid mode",
+			req:  httptest.NewRequest("POST", "/asdf?mode=yolo", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{`mode must be best-effort or atomic, not "yolo"`},
+			},
+			errMsg: "",
+		},
 		{
 			name: "error reading from request",
 			req:  httptest.NewRequest("POST", "/asdf", mockErrReader{}),
@@ -55,12 +103,22 @@ func TestPostRecord(t *testing.T) {
 			},
 			errMsg: "non-nil error",
 		},
+		{
+			name: "no records in the body",
+			req:  httptest.NewRequest("POST", "/asdf", strings.NewReader("\n  \n")),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"there were no records in the body"},
+			},
+			errMsg: "",
+		},
 		{
 			name: "error parsing line into record",
 			req:  httptest.NewRequest("POST", "/asdf", strings.NewReader("hey|there|you")),
 			wantResp: response.Structured{
 				StatusCode: 400,
-				Errors:     []string{"there were 3 fields when there should have been 5"},
+				Results:    []response.LineResult{{Line: 1, Errors: []string{"there were 3 fields when there should have been 5"}}},
+				Errors:     []string{"1: there were 3 fields when there should have been 5"},
 			},
 			errMsg: "",
 		},
@@ -69,40 +127,223 @@ func TestPostRecord(t *testing.T) {
 			req:  httptest.NewRequest("POST", "/asdf", strings.NewReader("Grey,Gandalf,Male,,1100-04-")),
 			wantResp: response.Structured{
 				StatusCode: 400,
-				Errors:     []string{"favorite color (field 4) must be a non-empty string", "date of birth (field 5) must have the format YYYY-MM-DD"},
+				Results: []response.LineResult{{Line: 1, Errors: []string{
+					"favorite color (field 4) must be a non-empty string",
+					"date of birth (field 5) must have the format YYYY-MM-DD",
+				}}},
+				Errors: []string{
+					"1: favorite color (field 4) must be a non-empty string",
+					"1: date of birth (field 5) must have the format YYYY-MM-DD",
+				},
 			},
 			errMsg: "",
 		},
 		{
-			name: "success (and we don't care about other lines)",
+			name:  "error adding the person",
+			req:   httptest.NewRequest("POST", "/asdf", strings.NewReader("Grey,Gandalf,Male,Rainbow,1100-04-03")),
+			adder: mockAdder{err: errors.New("disk full")},
+			wantResp: response.Structured{
+				StatusCode: 500,
+				Errors:     []string{"unexpected error"},
+			},
+			errMsg: "disk full",
+		},
+		{
+			name:      "success with one record",
+			req:       httptest.NewRequest("POST", "/asdf", strings.NewReader("Grey,Gandalf,Male,Rainbow,1100-04-03\n")),
+			wantAdded: []person.Person{gandalf},
+			wantResp: response.Structured{
+				StatusCode: 201,
+				Header:     http.Header{"Location": []string{"/records/11"}},
+				Data:       []person.Person{gandalfWithID},
+				Results:    []response.LineResult{{Line: 1, ID: 11}},
+			},
+			errMsg: "",
+		},
+		{
+			name: "success with many records in different formats",
 			req: httptest.NewRequest("POST", "/asdf", strings.NewReader(`Grey,Gandalf,Male,Rainbow,1100-04-03
-this
-is
-ignored`)),
-			wantPerson: person.Person{
-				LastName:      "Grey",
-				FirstName:     "Gandalf",
-				Gender:        "Male",
-				FavoriteColor: "Rainbow",
-				DateOfBirth:   time.Date(1100, 4, 3, 0, 0, 0, 0, time.UTC),
+
+Rohan Eowyn Female Gold 1950-07-27`)),
+			wantAdded: []person.Person{gandalf, eowyn},
+			wantResp: response.Structured{
+				StatusCode: 201,
+				Data:       []person.Person{gandalfWithID, eowynWithID},
+				Results:    []response.LineResult{{Line: 1, ID: 11}, {Line: 3, ID: 12}},
+			},
+			errMsg: "",
+		},
+		{
+			name: "some records are invalid",
+			req: httptest.NewRequest("POST", "/asdf", strings.NewReader(`this|is|wrong
+Grey,Gandalf,Male,Rainbow,1100-04-03
+Rohan Eowyn Female Gold 1950-07-27`)),
+			wantAdded: []person.Person{gandalf, eowyn},
+			wantResp: response.Structured{
+				StatusCode: 207,
+				Data:       []person.Person{gandalfWithID, eowynWithID},
+				Results: []response.LineResult{
+					{Line: 1, Errors: []string{"there were 3 fields when there should have been 5"}},
+					{Line: 2, ID: 11},
+					{Line: 3, ID: 12},
+				},
+				Errors: []string{"1: there were 3 fields when there should have been 5"},
+			},
+			errMsg: "",
+		},
+		{
+			name: "some records are invalid in atomic mode",
+			req: httptest.NewRequest("POST", "/asdf?mode=atomic", strings.NewReader(`this|is|wrong
+Grey,Gandalf,Male,Rainbow,1100-04-03
+Rohan Eowyn Female Gold
+`)),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Results: []response.LineResult{
+					{Line: 1, Errors: []string{"there were 3 fields when there should have been 5"}},
+					{Line: 2},
+					{Line: 3, Errors: []string{"there were 4 fields when there should have been 5"}},
+				},
+				Errors: []string{
+					"1: there were 3 fields when there should have been 5",
+					"3: there were 4 fields when there should have been 5",
+				},
 			},
+			errMsg: "",
+		},
+		{
+			name: "another dialect",
+			req: httptest.NewRequest("POST", "/asdf", strings.NewReader(`# exported from somewhere
+Grey ; Gandalf ; Male ; Rainbow ; 1100-04-03`)),
+			dialect: multicsv.Dialect{
+				Delimiters:    ";",
+				NumFields:     5,
+				Trim:          true,
+				CommentPrefix: "#",
+			},
+			wantAdded: []person.Person{gandalf},
 			wantResp: response.Structured{
-				StatusCode: 200,
+				StatusCode: 201,
+				Header:     http.Header{"Location": []string{"/records/11"}},
+				Data:       []person.Person{gandalfWithID},
+				Results:    []response.LineResult{{Line: 2, ID: 11}},
+			},
+			errMsg: "",
+		},
+		{
+			name: "header with reordered columns",
+			req: httptest.NewRequest("POST", "/asdf", strings.NewReader(`FirstName,LastName,DateOfBirth,Gender,FavoriteColor
+Gandalf,Grey,1100-04-03,Male,Rainbow
+Rohan|Eowyn|Female|Gold|1950-07-27`)),
+			wantAdded: []person.Person{gandalf},
+			wantResp: response.Structured{
+				StatusCode: 207,
+				Data:       []person.Person{gandalfWithID},
+				Results: []response.LineResult{
+					{Line: 2, ID: 11},
+					{Line: 3, Errors: []string{"date of birth (field 3) must have the format YYYY-MM-DD"}},
+				},
+				Errors: []string{
+					"3: date of birth (field 3) must have the format YYYY-MM-DD",
+				},
+			},
+			errMsg: "",
+		},
+		{
+			name: "invalid header",
+			req: httptest.NewRequest("POST", "/asdf", strings.NewReader(`FirstName,LastName,FirstName,Gender,FavoriteColor
+Gandalf,Grey,1100-04-03,Male,Rainbow`)),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Results: []response.LineResult{
+					{Line: 1, Errors: []string{
+						"the header names the first name column twice (fields 1 and 3)",
+						"the header has no date of birth column",
+					}},
+					{Line: 2, Errors: []string{"the order of the fields is unknown because the header is invalid"}},
+				},
+				Errors: []string{
+					"1: the header names the first name column twice (fields 1 and 3)",
+					"1: the header has no date of birth column",
+					"2: the order of the fields is unknown because the header is invalid",
+				},
+			},
+			errMsg: "",
+		},
+		{
+			name: "dates in several formats",
+			req: httptest.NewRequest("POST", "/asdf", strings.NewReader(`Grey,Gandalf,Male,Rainbow,11000403
+Rohan Eowyn Female Gold 27.07.1950
+Took|Pippin|Male|Green|03/04/1990`)),
+			dateFormats: []string{"YYYYMMDD", "DD.MM.YYYY", "M/D/YYYY", "D/M/YYYY"},
+			wantAdded:   []person.Person{gandalf, eowyn},
+			wantResp: response.Structured{
+				StatusCode: 207,
+				Data:       []person.Person{gandalfWithID, eowynWithID},
+				Results: []response.LineResult{
+					{Line: 1, ID: 11},
+					{Line: 2, ID: 12},
+					{Line: 3, Errors: []string{"date of birth (field 5) is ambiguous, it is 1990-03-04 as M/D/YYYY but 1990-04-03 as D/M/YYYY"}},
+				},
+				Errors: []string{"3: date of birth (field 5) is ambiguous, it is 1990-03-04 as M/D/YYYY but 1990-04-03 as D/M/YYYY"},
+			},
+			errMsg: "",
+		},
+		{
+			name:      "date formats given by the request",
+			req:       httptest.NewRequest("POST", "/asdf?date_formats=D/M/YYYY", strings.NewReader(`Grey,Gandalf,Male,Rainbow,3/4/1100`)),
+			wantAdded: []person.Person{gandalf},
+			wantResp: response.Structured{
+				StatusCode: 201,
+				Header:     http.Header{"Location": []string{"/records/11"}},
+				Data:       []person.Person{gandalfWithID},
+				Results:    []response.LineResult{{Line: 1, ID: 11}},
+			},
+			errMsg: "",
+		},
+		{
+			name:        "invalid date formats",
+			req:         httptest.NewRequest("POST", "/asdf?date_formats=", strings.NewReader(`Grey,Gandalf,Male,Rainbow,1100-04-03`)),
+			dateFormats: person.DefaultDateFormats,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"date_formats: the list of date formats is empty"},
+			},
+			errMsg: "",
+		},
+		{
+			name: "success in atomic mode",
+			req: httptest.NewRequest("POST", "/asdf?mode=atomic", strings.NewReader(`Grey,Gandalf,Male,Rainbow,1100-04-03
+Rohan Eowyn Female Gold 1950-07-27`)),
+			wantAdded: []person.Person{gandalf, eowyn},
+			wantResp: response.Structured{
+				StatusCode: 201,
+				Data:       []person.Person{gandalfWithID, eowynWithID},
+				Results:    []response.LineResult{{Line: 1, ID: 11}, {Line: 2, ID: 12}},
 			},
 			errMsg: "",
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			p, resp, err := postrecord.PostRecord(test.req)
+			d := test.dialect
+			if d.Delimiters == "" {
+				d = multicsv.DefaultDialect
+			}
+			// Most cases only need the one date format.
+			dateFormats := test.dateFormats
+			if dateFormats == nil {
+				dateFormats = []string{"YYYY-MM-DD"}
+			}
+			resp, err := postrecord.PostRecord(test.req, &test.adder, d, dateFormats)
 			if got, want := errToStr(err), test.errMsg; got != want {
 				t.Errorf("got error %q, want %q", got, want)
 			}
 			if got, want := resp, test.wantResp; !reflect.DeepEqual(got, want) {
 				t.Errorf("got resp %+v, want %+v", got, want)
 			}
-			if got, want := p, test.wantPerson; got != want {
-				t.Errorf("got person %+v, want %+v", got, want)
+			if got, want := test.adder.added, test.wantAdded; !reflect.DeepEqual(got, want) {
+				t.Errorf("got added persons %+v, want %+v", got, want)
 			}
 		})
 	}
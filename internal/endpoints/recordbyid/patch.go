@@ -0,0 +1,143 @@
+package recordbyid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lag13/records/internal/mergepatch"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+// The JSON fields of a person.Person in the order of
+// person.DefaultColumns.
+var patchableFields = []string{"last_name", "first_name", "gender", "favorite_color", "birthdate"}
+
+// errRejected is returned from a modification when the patched record
+// is invalid so nothing is changed.
+var errRejected = errors.New("the patched record was rejected")
+
+// patch applies a JSON merge patch to the JSON representation of the
+// record. The result goes through the same validation as a record
+// which was POSTed, so a patched birthdate can be in any of the
+// dateFormats or those of the date_formats query parameter. The
+// record is read and replaced in one step so concurrent patches do
+// not undo each other.
+func patch(req *http.Request, id int, s Store, dateFormats []string) (response.Structured, error) {
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			return response.Structured{
+				StatusCode: http.StatusUnsupportedMediaType,
+				Errors:     []string{fmt.Sprintf("the Content-Type must be application/merge-patch+json, not %s", contentType)},
+			}, nil
+		}
+	}
+	dateFormats, errs := person.DateFormatsFromQuery(req.URL.Query(), dateFormats)
+	if len(errs) > 0 {
+		return badRequest(errs...), nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return unexpectedErr, err
+	}
+	var rejected response.Structured
+	var p person.Person
+	err = s.Modify(id, func(cur person.Person) (person.Person, error) {
+		doc, err := json.Marshal(cur)
+		if err != nil {
+			panic(err)
+		}
+		patched, err := mergepatch.Apply(doc, body)
+		if err != nil {
+			rejected = badRequest(fmt.Sprintf("the body is not a valid JSON merge patch: %v", err))
+			return person.Person{}, errRejected
+		}
+		fields, errs := patchedFields(patched, id)
+		if len(errs) > 0 {
+			rejected = badRequest(errs...)
+			return person.Person{}, errRejected
+		}
+		formats := dateFormats
+		// A birthdate is sent in the same format it is returned
+		// in, which is not one of the date formats, when it is
+		// left alone.
+		if t, err := time.Parse(time.RFC3339, fields[4]); err == nil {
+			fields[4] = t.Format("2006-01-02")
+			formats = []string{"YYYY-MM-DD"}
+		}
+		var parseErrs []string
+		p, parseErrs = person.DefaultColumns.Parse(fields, formats)
+		if len(parseErrs) > 0 {
+			rejected = badRequest(parseErrs...)
+			return person.Person{}, errRejected
+		}
+		return p, nil
+	})
+	if err == errRejected {
+		return rejected, nil
+	}
+	if err != nil {
+		return unexpectedErr, err
+	}
+	p.ID = id
+	return response.Structured{
+		StatusCode: http.StatusOK,
+		Data:       []person.Person{p},
+	}, nil
+}
+
+func badRequest(errs ...string) response.Structured {
+	return response.Structured{
+		StatusCode: http.StatusBadRequest,
+		Errors:     errs,
+	}
+}
+
+// patchedFields converts a patched JSON record into the fields, in the
+// order of person.DefaultColumns, of a record. Fields which were
+// removed by the patch become empty strings so parsing them complains.
+func patchedFields(patched []byte, id int) ([]string, []string) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(patched, &obj); err != nil {
+		return nil, []string{"the patched record must be a JSON object"}
+	}
+	errs := []string{}
+	if gotID, ok := obj["id"].(float64); !ok || int(gotID) != id {
+		errs = append(errs, "id cannot be changed")
+	}
+	delete(obj, "id")
+	fields := make([]string, len(patchableFields))
+	for i, name := range patchableFields {
+		value, ok := obj[name]
+		delete(obj, name)
+		if !ok {
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s must be a string", name))
+			continue
+		}
+		fields[i] = str
+	}
+	// Records are returned with their age, which is worked out
+	// from the birthdate, so a client sending back a record it got
+	// may include it.
+	delete(obj, "age")
+	unknown := []string{}
+	for name := range obj {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, fmt.Sprintf("%s is not a field of a record", name))
+	}
+	return fields, errs
+}
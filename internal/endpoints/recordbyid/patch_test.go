@@ -0,0 +1,186 @@
+package recordbyid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/endpoints/recordbyid"
+	"github.com/lag13/records/internal/multicsv"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+func newPatchRequest(contentType string, body string) *http.Request {
+	return newPatchRequestTo("/records/3", contentType, body)
+}
+
+func newPatchRequestTo(target string, contentType string, body string) *http.Request {
+	req := httptest.NewRequest("PATCH", target, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func TestHandlePatch(t *testing.T) {
+	frodo := person.Person{
+		ID:            3,
+		LastName:      "Baggins",
+		FirstName:     "Frodo",
+		Gender:        "Male",
+		FavoriteColor: "Green",
+		DateOfBirth:   time.Date(1900, time.September, 22, 0, 0, 0, 0, time.UTC),
+	}
+	patchedFrodo := frodo
+	patchedFrodo.FavoriteColor = "Grey"
+	patchedFrodo.DateOfBirth = time.Date(1968, time.September, 22, 0, 0, 0, 0, time.UTC)
+	greyFrodo := frodo
+	greyFrodo.FavoriteColor = "Grey"
+	tests := []struct {
+		name        string
+		req         *http.Request
+		dateFormats []string
+		wantResp    response.Structured
+		wantPerson  person.Person
+	}{
+		{
+			name: "unsupported content type",
+			req:  newPatchRequest("text/plain", `{"favorite_color":"Grey"}`),
+			wantResp: response.Structured{
+				StatusCode: 415,
+				Errors:     []string{"the Content-Type must be application/merge-patch+json, not text/plain"},
+			},
+			wantPerson: frodo,
+		},
+		{
+			name: "body is not JSON",
+			req:  newPatchRequest("application/merge-patch+json", `{"favorite_color":`),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"the body is not a valid JSON merge patch: unexpected end of JSON input"},
+			},
+			wantPerson: frodo,
+		},
+		{
+			name: "patch replaces the whole record",
+			req:  newPatchRequest("application/merge-patch+json", `["Grey"]`),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"the patched record must be a JSON object"},
+			},
+			wantPerson: frodo,
+		},
+		{
+			name: "patch has invalid fields",
+			req:  newPatchRequest("application/merge-patch+json", `{"id":4,"gender":3,"nickname":"Mr. Underhill"}`),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors: []string{
+					"id cannot be changed",
+					"gender must be a string",
+					"nickname is not a field of a record",
+				},
+			},
+			wantPerson: frodo,
+		},
+		{
+			name: "patched record is invalid",
+			req:  newPatchRequest("application/merge-patch+json", `{"first_name":null,"birthdate":"yesterday"}`),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors: []string{
+					"first name (field 2) must be a non-empty string",
+					"date of birth (field 5) must have one of the formats YYYY-MM-DD, M/D/YYYY, DD.MM.YYYY, YYYYMMDD",
+				},
+			},
+			wantPerson: frodo,
+		},
+		{
+			name: "success",
+			req:  newPatchRequest("application/merge-patch+json", `{"id":3,"favorite_color":"Grey","birthdate":"1968-09-22"}`),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{patchedFrodo},
+			},
+			wantPerson: patchedFrodo,
+		},
+		{
+			name: "birthdate in the format it is returned in",
+			req:  newPatchRequest("application/json", `{"favorite_color":"Grey","birthdate":"1968-09-22T00:00:00Z"}`),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{patchedFrodo},
+			},
+			wantPerson: patchedFrodo,
+		},
+		{
+			name: "the age a record is returned with is ignored",
+			req:  newPatchRequest("application/json", `{"favorite_color":"Grey","birthdate":"1968-09-22","age":50}`),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{patchedFrodo},
+			},
+			wantPerson: patchedFrodo,
+		},
+		{
+			name:        "birthdate in a configured date format",
+			req:         newPatchRequest("application/json", `{"favorite_color":"Grey","birthdate":"22/9/1968"}`),
+			dateFormats: []string{"D/M/YYYY"},
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{patchedFrodo},
+			},
+			wantPerson: patchedFrodo,
+		},
+		{
+			name:        "birthdate left alone with configured date formats",
+			req:         newPatchRequest("application/json", `{"favorite_color":"Grey"}`),
+			dateFormats: []string{"D/M/YYYY"},
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{greyFrodo},
+			},
+			wantPerson: greyFrodo,
+		},
+		{
+			name: "birthdate in a date format from the query",
+			req:  newPatchRequestTo("/records/3?date_formats=D/M/YYYY", "application/json", `{"favorite_color":"Grey","birthdate":"22/9/1968"}`),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{patchedFrodo},
+			},
+			wantPerson: patchedFrodo,
+		},
+		{
+			name: "unknown date formats in the query",
+			req:  newPatchRequestTo("/records/3?date_formats=YYYY/MM/DD", "application/json", `{"birthdate":"1968/09/22"}`),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{`date_formats: unknown date format "YYYY/MM/DD", known formats are D/M/YYYY, DD.MM.YYYY, M/D/YYYY, YYYY-MM-DD, YYYYMMDD`},
+			},
+			wantPerson: frodo,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := frodo
+			s := &mockStore{p: &p}
+			dateFormats := test.dateFormats
+			if dateFormats == nil {
+				dateFormats = person.DefaultDateFormats
+			}
+			resp, err := recordbyid.Handle(test.req, s, multicsv.DefaultDialect, dateFormats)
+			if err != nil {
+				t.Errorf("got unexpected error %v", err)
+			}
+			if got, want := resp, test.wantResp; !reflect.DeepEqual(got, want) {
+				t.Errorf("got resp %+v, want %+v", got, want)
+			}
+			if got, want := *s.p, test.wantPerson; got != want {
+				t.Errorf("got stored person %+v, want %+v", got, want)
+			}
+		})
+	}
+}
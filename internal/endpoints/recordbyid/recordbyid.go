@@ -0,0 +1,161 @@
+// Package recordbyid defines a handler for requests which operate on
+// the single record identified by the id at the end of the URL path.
+package recordbyid
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lag13/records/internal/fields"
+	"github.com/lag13/records/internal/multicsv"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+	"github.com/lag13/records/internal/store"
+)
+
+// Store is the subset of store.Store which operates on a single
+// record.
+type Store interface {
+	Get(id int) (person.Person, error)
+	Update(id int, p person.Person) error
+	Modify(id int, fn func(person.Person) (person.Person, error)) error
+	Delete(id int) error
+}
+
+const pathPrefix = "/records/"
+
+// Location returns the path at which the record with the given id
+// can be found.
+func Location(id int) string {
+	return fmt.Sprintf("%s%d", pathPrefix, id)
+}
+
+// parseID returns the id in a URL path like /records/{id}.
+func parseID(req *http.Request) (int, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, pathPrefix))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+func notFound(req *http.Request) response.Structured {
+	return response.Structured{
+		StatusCode: http.StatusNotFound,
+		Errors:     []string{fmt.Sprintf("there is no record at %s", req.URL.Path)},
+	}
+}
+
+var unexpectedErr = response.Structured{
+	StatusCode: http.StatusInternalServerError,
+	Errors:     []string{"unexpected error"},
+}
+
+// Handle gets (GET), replaces (PUT), partially updates (PATCH) or
+// deletes (DELETE) the record identified by the request. The body of
+// a PUT is one line, besides blank lines and comments, in any of the
+// formats of the dialect, which should be the one used when POSTing
+// records, and the body of a PATCH is a JSON merge patch. A date of
+// birth in either can be in any of the dateFormats, unless the
+// date_formats query parameter gives others.
+func Handle(req *http.Request, s Store, d multicsv.Dialect, dateFormats []string) (response.Structured, error) {
+	handlers := map[string]func(*http.Request, int, Store) (response.Structured, error){
+		http.MethodGet: get,
+		http.MethodPut: func(req *http.Request, id int, s Store) (response.Structured, error) {
+			return put(req, id, s, d, dateFormats)
+		},
+		http.MethodPatch: func(req *http.Request, id int, s Store) (response.Structured, error) {
+			return patch(req, id, s, dateFormats)
+		},
+		http.MethodDelete: del,
+	}
+	handler, ok := handlers[req.Method]
+	if !ok {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     []string{fmt.Sprintf("this endpoint works with a GET, PUT, PATCH or DELETE request, not a %s", req.Method)},
+		}, nil
+	}
+	id, ok := parseID(req)
+	if !ok {
+		return notFound(req), nil
+	}
+	resp, err := handler(req, id, s)
+	if err == store.ErrNotFound {
+		return notFound(req), nil
+	}
+	return resp, err
+}
+
+// get returns the record. Like the list endpoints, the fields query
+// parameter chooses which fields of it are returned.
+func get(req *http.Request, id int, s Store) (response.Structured, error) {
+	fieldNames, errs := fields.FromQuery(req.URL.Query())
+	if len(errs) > 0 {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     errs,
+		}, nil
+	}
+	p, err := s.Get(id)
+	if err != nil {
+		return unexpectedErr, err
+	}
+	return response.Structured{
+		StatusCode: http.StatusOK,
+		Fields:     fieldNames,
+		Data:       []person.Person{p},
+	}, nil
+}
+
+func put(req *http.Request, id int, s Store, d multicsv.Dialect, dateFormats []string) (response.Structured, error) {
+	dateFormats, errs := person.DateFormatsFromQuery(req.URL.Query(), dateFormats)
+	if len(errs) > 0 {
+		return badRequest(errs...), nil
+	}
+	lines := []string{}
+	scanner := bufio.NewScanner(req.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || d.IsComment(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err == bufio.ErrTooLong {
+		return badRequest("the line is too long"), nil
+	} else if err != nil {
+		return unexpectedErr, err
+	}
+	if len(lines) == 0 {
+		return badRequest("there was no record in the body"), nil
+	}
+	if len(lines) > 1 {
+		return badRequest(fmt.Sprintf("the body must be one record but it has %d lines", len(lines))), nil
+	}
+	p, isHeader, parseErrs := person.NewLineParser(d, dateFormats).ParseLine(lines[0])
+	if len(parseErrs) > 0 {
+		return badRequest(parseErrs...), nil
+	}
+	if isHeader {
+		return badRequest("the body must be a record, not a header"), nil
+	}
+	if err := s.Update(id, p); err != nil {
+		return unexpectedErr, err
+	}
+	p.ID = id
+	return response.Structured{
+		StatusCode: http.StatusOK,
+		Data:       []person.Person{p},
+	}, nil
+}
+
+func del(req *http.Request, id int, s Store) (response.Structured, error) {
+	if err := s.Delete(id); err != nil {
+		return unexpectedErr, err
+	}
+	return response.Structured{StatusCode: http.StatusNoContent}, nil
+}
@@ -0,0 +1,283 @@
+package recordbyid_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/endpoints/recordbyid"
+	"github.com/lag13/records/internal/multicsv"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+	"github.com/lag13/records/internal/store"
+)
+
+func errToStr(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprint(err)
+}
+
+// mockStore holds at most one person and fails every call if err is
+// set.
+type mockStore struct {
+	p   *person.Person
+	err error
+}
+
+func (m *mockStore) Get(id int) (person.Person, error) {
+	if m.err != nil {
+		return person.Person{}, m.err
+	}
+	if m.p == nil || id != m.p.ID {
+		return person.Person{}, store.ErrNotFound
+	}
+	return *m.p, nil
+}
+
+func (m *mockStore) Update(id int, p person.Person) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	p.ID = id
+	m.p = &p
+	return nil
+}
+
+func (m *mockStore) Modify(id int, fn func(person.Person) (person.Person, error)) error {
+	cur, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+	p, err := fn(cur)
+	if err != nil {
+		return err
+	}
+	return m.Update(id, p)
+}
+
+func (m *mockStore) Delete(id int) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	m.p = nil
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	gandalf := person.Person{ID: 7, LastName: "Grey", FirstName: "Gandalf"}
+	saruman := person.Person{
+		ID:            7,
+		LastName:      "White",
+		FirstName:     "Saruman",
+		Gender:        "Male",
+		FavoriteColor: "White",
+		DateOfBirth:   time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name       string
+		req        *http.Request
+		store      mockStore
+		wantResp   response.Structured
+		wantPerson *person.Person
+		errMsg     string
+	}{
+		{
+			name: "invalid http method",
+			req:  httptest.NewRequest("POST", "/records/7", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"this endpoint works with a GET, PUT, PATCH or DELETE request, not a POST"},
+			},
+		},
+		{
+			name: "id is not a number",
+			req:  httptest.NewRequest("GET", "/records/seven", nil),
+			wantResp: response.Structured{
+				StatusCode: 404,
+				Errors:     []string{"there is no record at /records/seven"},
+			},
+		},
+		{
+			name: "path is nested too deeply",
+			req:  httptest.NewRequest("GET", "/records/gender/7", nil),
+			wantResp: response.Structured{
+				StatusCode: 404,
+				Errors:     []string{"there is no record at /records/gender/7"},
+			},
+		},
+		{
+			name:       "record does not exist",
+			req:        httptest.NewRequest("GET", "/records/8", nil),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 404,
+				Errors:     []string{"there is no record at /records/8"},
+			},
+		},
+		{
+			name:  "error getting the record",
+			req:   httptest.NewRequest("GET", "/records/7", nil),
+			store: mockStore{err: errors.New("disk on fire")},
+			wantResp: response.Structured{
+				StatusCode: 500,
+				Errors:     []string{"unexpected error"},
+			},
+			errMsg: "disk on fire",
+		},
+		{
+			name:       "get the record",
+			req:        httptest.NewRequest("GET", "/records/7", nil),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{gandalf},
+			},
+		},
+		{
+			name:       "get some fields of the record",
+			req:        httptest.NewRequest("GET", "/records/7?fields=first_name,id", nil),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Fields:     []string{"first_name", "id"},
+				Data:       []person.Person{gandalf},
+			},
+		},
+		{
+			name:       "get unknown fields of the record",
+			req:        httptest.NewRequest("GET", "/records/7?fields=height", nil),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"fields: unknown field \"height\", allowed fields are id, last_name, first_name, gender, favorite_color, birthdate, age"},
+			},
+		},
+		{
+			name:       "replace with an invalid record",
+			req:        httptest.NewRequest("PUT", "/records/7", strings.NewReader("White|Saruman|Male")),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"there were 3 fields when there should have been 5"},
+			},
+		},
+		{
+			name:       "replace with a header",
+			req:        httptest.NewRequest("PUT", "/records/7", strings.NewReader("LastName|FirstName|Gender|Color|DOB")),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"the body must be a record, not a header"},
+			},
+		},
+		{
+			name:       "replace with several records",
+			req:        httptest.NewRequest("PUT", "/records/7", strings.NewReader("White|Saruman|Male|White|1000-01-01\ngarbage")),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"the body must be one record but it has 2 lines"},
+			},
+		},
+		{
+			name:       "replace with nothing",
+			req:        httptest.NewRequest("PUT", "/records/7", strings.NewReader("\n  \n")),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"there was no record in the body"},
+			},
+		},
+		{
+			name: "replace a record which does not exist",
+			req:  httptest.NewRequest("PUT", "/records/7", strings.NewReader("White|Saruman|Male|White|1000-01-01")),
+			wantResp: response.Structured{
+				StatusCode: 404,
+				Errors:     []string{"there is no record at /records/7"},
+			},
+		},
+		{
+			name:       "replace the record",
+			req:        httptest.NewRequest("PUT", "/records/7", strings.NewReader("White|Saruman|Male|White|1000-01-01")),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &saruman,
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{saruman},
+			},
+		},
+		{
+			name:       "blank lines around the record are ignored",
+			req:        httptest.NewRequest("PUT", "/records/7", strings.NewReader("\nWhite|Saruman|Male|White|1000-01-01\n\n")),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &saruman,
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{saruman},
+			},
+		},
+		{
+			name:       "replace the record with a date in another format",
+			req:        httptest.NewRequest("PUT", "/records/7?date_formats=D/M/YYYY", strings.NewReader("White|Saruman|Male|White|1/1/1000")),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &saruman,
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{saruman},
+			},
+		},
+		{
+			name:       "replace with unknown date formats",
+			req:        httptest.NewRequest("PUT", "/records/7?date_formats=YYYY/MM/DD", strings.NewReader("White|Saruman|Male|White|1000/01/01")),
+			store:      mockStore{p: &gandalf},
+			wantPerson: &gandalf,
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{`date_formats: unknown date format "YYYY/MM/DD", known formats are D/M/YYYY, DD.MM.YYYY, M/D/YYYY, YYYY-MM-DD, YYYYMMDD`},
+			},
+		},
+		{
+			name: "delete a record which does not exist",
+			req:  httptest.NewRequest("DELETE", "/records/7", nil),
+			wantResp: response.Structured{
+				StatusCode: 404,
+				Errors:     []string{"there is no record at /records/7"},
+			},
+		},
+		{
+			name:     "delete the record",
+			req:      httptest.NewRequest("DELETE", "/records/7", nil),
+			store:    mockStore{p: &gandalf},
+			wantResp: response.Structured{StatusCode: 204},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := recordbyid.Handle(test.req, &test.store, multicsv.DefaultDialect, person.DefaultDateFormats)
+			if got, want := errToStr(err), test.errMsg; got != want {
+				t.Errorf("got error %q, want %q", got, want)
+			}
+			if got, want := resp, test.wantResp; !reflect.DeepEqual(got, want) {
+				t.Errorf("got resp %+v, want %+v", got, want)
+			}
+			if got, want := test.store.p, test.wantPerson; !reflect.DeepEqual(got, want) {
+				t.Errorf("got stored person %+v, want %+v", got, want)
+			}
+		})
+	}
+}
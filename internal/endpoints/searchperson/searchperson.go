@@ -0,0 +1,31 @@
+// Package searchperson defines a handler which finds people by name.
+package searchperson
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lag13/records/internal/endpoints/getsortperson"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+	"github.com/lag13/records/internal/search"
+)
+
+// QueryParam is the query parameter holding the name to search for.
+const QueryParam = "q"
+
+// Search returns the people whose first or last name matches the "q"
+// query parameter, best matches first. The matching is forgiving of
+// case, accents and typos (see the search package). Like the sorted
+// endpoints the results can be filtered and paginated.
+func Search(req *http.Request, ps []person.Person) response.Structured {
+	var errs []string
+	q := req.URL.Query().Get(QueryParam)
+	if strings.TrimSpace(q) == "" {
+		errs = append(errs, fmt.Sprintf("the %s query parameter must contain a name to search for", QueryParam))
+	}
+	return getsortperson.List(req, ps, func(ps []person.Person) []person.Person {
+		return search.Search(q, ps)
+	}, errs, QueryParam)
+}
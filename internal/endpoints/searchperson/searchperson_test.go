@@ -0,0 +1,101 @@
+package searchperson_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/lag13/records/internal/endpoints/searchperson"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+func TestSearch(t *testing.T) {
+	ps := []person.Person{
+		{ID: 1, LastName: "Baggins", FirstName: "Bilbo", Gender: "male"},
+		{ID: 2, LastName: "Gamgee", FirstName: "Samwise", Gender: "male"},
+		{ID: 3, LastName: "Baggins", FirstName: "Frodo", Gender: "male"},
+		{ID: 4, LastName: "Sackville-Baggins", FirstName: "Lobelia", Gender: "female"},
+	}
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantResp response.Structured
+	}{
+		{
+			name: "wrong http method",
+			req:  httptest.NewRequest("POST", "/records/search?q=frodo", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"this endpoint works with a GET request, not a POST"},
+			},
+		},
+		{
+			name: "invalid query parameters",
+			req:  httptest.NewRequest("GET", "/records/search?limit=0&colour=red", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors: []string{
+					"the q query parameter must contain a name to search for",
+					"limit must be an integer between 1 and 1000",
+					`unknown query parameter "colour"`,
+				},
+			},
+		},
+		{
+			name: "best matches come first",
+			req:  httptest.NewRequest("GET", "/records/search?q=Bagins", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data: []person.Person{
+					{ID: 1, LastName: "Baggins", FirstName: "Bilbo", Gender: "male"},
+					{ID: 3, LastName: "Baggins", FirstName: "Frodo", Gender: "male"},
+					{ID: 4, LastName: "Sackville-Baggins", FirstName: "Lobelia", Gender: "female"},
+				},
+			},
+		},
+		{
+			name: "filtered",
+			req:  httptest.NewRequest("GET", "/records/search?q=baggins&gender=female", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data: []person.Person{
+					{ID: 4, LastName: "Sackville-Baggins", FirstName: "Lobelia", Gender: "female"},
+				},
+			},
+		},
+		{
+			name: "paginated",
+			req:  httptest.NewRequest("GET", "/records/search?q=baggins&limit=1&offset=1", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data: []person.Person{
+					{ID: 3, LastName: "Baggins", FirstName: "Frodo", Gender: "male"},
+				},
+				Pagination: &response.Pagination{
+					Total:  3,
+					Limit:  1,
+					Offset: 1,
+					Next:   "/records/search?limit=1&offset=2&q=baggins",
+				},
+			},
+		},
+		{
+			name: "no matches",
+			req:  httptest.NewRequest("GET", "/records/search?q=gollum", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				Data:       []person.Person{},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotResp := searchperson.Search(test.req, ps)
+			if !reflect.DeepEqual(gotResp, test.wantResp) {
+				t.Errorf("got response %+v, want %+v", gotResp, test.wantResp)
+			}
+		})
+	}
+}
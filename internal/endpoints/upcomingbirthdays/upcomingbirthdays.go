@@ -0,0 +1,71 @@
+// Package upcomingbirthdays defines a handler which returns the people
+// whose birthdays are coming up.
+package upcomingbirthdays
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lag13/records/internal/birthdays"
+	"github.com/lag13/records/internal/fields"
+	"github.com/lag13/records/internal/filter"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+const (
+	// DaysParam is the query parameter holding how many days
+	// ahead to look for birthdays.
+	DaysParam = "days"
+	// DefaultDays is used when there is no days query parameter.
+	DefaultDays = 30
+	// MaxDays is the largest allowed number of days. Everybody
+	// has a birthday within a year so there is no point looking
+	// further.
+	MaxDays = 366
+)
+
+// Upcoming returns the people whose next birthday is within the
+// number of days in the "days" query parameter, soonest first. Today,
+// or the date in the as_of query parameter, counts as day 0. The
+// people can first be filtered using the query parameters understood
+// by the filter package.
+func Upcoming(req *http.Request, ps []person.Person, now time.Time) response.Structured {
+	if req.Method != http.MethodGet {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     []string{fmt.Sprintf("this endpoint works with a GET request, not a %s", req.Method)},
+		}
+	}
+	query := req.URL.Query()
+	errs := []string{}
+	days := DefaultDays
+	if values, ok := query[DaysParam]; ok {
+		var err error
+		days, err = strconv.Atoi(values[0])
+		if err != nil || days < 0 || days > MaxDays || len(values) > 1 {
+			errs = append(errs, fmt.Sprintf("%s must be given once and be an integer between 0 and %d", DaysParam, MaxDays))
+		}
+	}
+	fieldNames, fieldErrs := fields.FromQuery(query)
+	errs = append(errs, fieldErrs...)
+	f, filterErrs := filter.Parse(query, DaysParam, fields.Param)
+	errs = append(errs, filterErrs...)
+	if len(errs) > 0 {
+		return response.Structured{
+			StatusCode: http.StatusBadRequest,
+			Errors:     errs,
+		}
+	}
+	if !f.AsOf.IsZero() {
+		now = f.AsOf
+	}
+	return response.Structured{
+		StatusCode: http.StatusOK,
+		AsOf:       now,
+		Fields:     fieldNames,
+		Data:       birthdays.Upcoming(f.Apply(ps), now, days),
+	}
+}
@@ -0,0 +1,93 @@
+package upcomingbirthdays_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/endpoints/upcomingbirthdays"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestUpcoming(t *testing.T) {
+	ps := []person.Person{
+		{ID: 1, Gender: "female", DateOfBirth: date(2000, time.February, 29)},
+		{ID: 2, Gender: "male", DateOfBirth: date(1990, time.March, 20)},
+		{ID: 3, Gender: "male", DateOfBirth: date(1980, time.February, 28)},
+		{ID: 4, Gender: "female", DateOfBirth: date(1970, time.January, 10)},
+	}
+	now := date(2021, time.February, 20)
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantResp response.Structured
+	}{
+		{
+			name: "wrong http method",
+			req:  httptest.NewRequest("POST", "/records/birthdays/upcoming", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors:     []string{"this endpoint works with a GET request, not a POST"},
+			},
+		},
+		{
+			name: "invalid query parameters",
+			req:  httptest.NewRequest("GET", "/records/birthdays/upcoming?days=400&as_of=today", nil),
+			wantResp: response.Structured{
+				StatusCode: 400,
+				Errors: []string{
+					"days must be given once and be an integer between 0 and 366",
+					"as_of must have the format YYYY-MM-DD",
+				},
+			},
+		},
+		{
+			name: "the next 30 days by default",
+			req:  httptest.NewRequest("GET", "/records/birthdays/upcoming", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				AsOf:       now,
+				Data: []person.Person{
+					{ID: 3, Gender: "male", DateOfBirth: date(1980, time.February, 28)},
+					{ID: 1, Gender: "female", DateOfBirth: date(2000, time.February, 29)},
+					{ID: 2, Gender: "male", DateOfBirth: date(1990, time.March, 20)},
+				},
+			},
+		},
+		{
+			name: "around the new year",
+			req:  httptest.NewRequest("GET", "/records/birthdays/upcoming?days=14&as_of=2020-12-31&gender=female", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				AsOf:       date(2020, time.December, 31),
+				Data: []person.Person{
+					{ID: 4, Gender: "female", DateOfBirth: date(1970, time.January, 10)},
+				},
+			},
+		},
+		{
+			name: "no upcoming birthdays",
+			req:  httptest.NewRequest("GET", "/records/birthdays/upcoming?days=0", nil),
+			wantResp: response.Structured{
+				StatusCode: 200,
+				AsOf:       now,
+				Data:       []person.Person{},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotResp := upcomingbirthdays.Upcoming(test.req, ps, now)
+			if !reflect.DeepEqual(gotResp, test.wantResp) {
+				t.Errorf("got response %+v, want %+v", gotResp, test.wantResp)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Package fields lets only some of the fields of a person be output
+// and in a chosen order.
+package fields
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lag13/records/internal/multicsv"
+	"github.com/lag13/records/internal/person"
+)
+
+// The names of the fields, which are the same as their JSON names.
+const (
+	ID            = "id"
+	LastName      = "last_name"
+	FirstName     = "first_name"
+	Gender        = "gender"
+	FavoriteColor = "favorite_color"
+	Birthdate     = "birthdate"
+	Age           = "age"
+)
+
+// Person are the fields which every person has.
+var Person = []string{LastName, FirstName, Gender, FavoriteColor, Birthdate}
+
+// Record are the fields of a record returned by the API.
+var Record = []string{ID, LastName, FirstName, Gender, FavoriteColor, Birthdate, Age}
+
+// aliases are other names a field can be given by, written the way
+// normalize leaves them.
+var aliases = map[string]string{
+	"color": FavoriteColor,
+}
+
+// normalize returns a field name in lower case without underscores.
+func normalize(name string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(name)), "_", "", -1)
+}
+
+// Lookup returns the canonical name of the field, which must be among
+// the allowed ones, that raw names. Names are case insensitive,
+// underscores are optional so lastname works as well as last_name and
+// a field can also be named by one of its aliases, like color.
+func Lookup(raw string, allowed []string) (string, bool) {
+	key := normalize(raw)
+	if name, ok := aliases[key]; ok {
+		key = normalize(name)
+	}
+	for _, name := range allowed {
+		if normalize(name) == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Param is the query parameter which holds the fields to return.
+const Param = "fields"
+
+// Parse parses a comma separated list of field names, which must be
+// among the allowed ones, and returns their canonical names (see
+// Lookup).
+func Parse(s string, allowed []string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("the list of fields is empty")
+	}
+	names := []string{}
+	seen := map[string]bool{}
+	for i, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return nil, fmt.Errorf("field %d is empty", i+1)
+		}
+		name, ok := Lookup(raw, allowed)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q, allowed fields are %s", raw, strings.Join(allowed, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("the field %s is given more than once", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// FromQuery returns the fields of a record asked for by the fields
+// query parameter or nil, meaning all of them, if there is none.
+func FromQuery(q url.Values) ([]string, []string) {
+	values, ok := q[Param]
+	if !ok {
+		return nil, nil
+	}
+	if len(values) > 1 {
+		return nil, []string{fmt.Sprintf("%s can only be given once", Param)}
+	}
+	names, err := Parse(values[0], Record)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("%s: %v", Param, err)}
+	}
+	return names, nil
+}
+
+// Marshal is like person.Marshal except only the given fields, which
+// must be among Person, are output and in the given order. Values are
+// quoted in the same way.
+func Marshal(p person.Person, names []string) string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		switch name {
+		case LastName:
+			values[i] = p.LastName
+		case FirstName:
+			values[i] = p.FirstName
+		case Gender:
+			values[i] = p.Gender
+		case FavoriteColor:
+			values[i] = p.FavoriteColor
+		case Birthdate:
+			values[i] = person.FormatDate(p.DateOfBirth)
+		}
+	}
+	return multicsv.Join(values, ',', multicsv.DefaultDialect.Delimiters)
+}
@@ -0,0 +1,80 @@
+package fields_test
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/fields"
+	"github.com/lag13/records/internal/person"
+)
+
+func errToStr(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s         string
+		allowed   []string
+		wantNames []string
+		wantErr   string
+	}{
+		{"last_name,birthdate", fields.Person, []string{"last_name", "birthdate"}, ""},
+		{" BirthDate , LastName,color", fields.Person, []string{"birthdate", "last_name", "favorite_color"}, ""},
+		{"age,id", fields.Record, []string{"age", "id"}, ""},
+		{"", fields.Person, nil, "the list of fields is empty"},
+		{"gender,", fields.Person, nil, "field 2 is empty"},
+		{"age", fields.Person, nil, `unknown field "age", allowed fields are last_name, first_name, gender, favorite_color, birthdate`},
+		{"gender,Gender", fields.Person, nil, "the field gender is given more than once"},
+	}
+	for _, test := range tests {
+		names, err := fields.Parse(test.s, test.allowed)
+		if got, want := errToStr(err), test.wantErr; got != want {
+			t.Errorf("%q: got error %q, want %q", test.s, got, want)
+		}
+		if got, want := names, test.wantNames; !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got names %v, want %v", test.s, got, want)
+		}
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantNames []string
+		wantErrs  []string
+	}{
+		{"", nil, nil},
+		{"fields=id,age", []string{"id", "age"}, nil},
+		{"fields=id&fields=age", nil, []string{"fields can only be given once"}},
+		{"fields=", nil, []string{"fields: the list of fields is empty"}},
+	}
+	for _, test := range tests {
+		q, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names, errs := fields.FromQuery(q)
+		if got, want := names, test.wantNames; !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got names %v, want %v", test.query, got, want)
+		}
+		if got, want := errs, test.wantErrs; !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got errors %v, want %v", test.query, got, want)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	p := person.Person{LastName: "Last", FirstName: "First", Gender: "Gender", FavoriteColor: "Color", DateOfBirth: time.Date(2003, time.May, 15, 0, 0, 0, 0, time.UTC)}
+	if got, want := fields.Marshal(p, []string{"birthdate", "first_name"}), "05/15/2003,First"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := fields.Marshal(p, fields.Person), person.Marshal(p); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
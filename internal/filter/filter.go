@@ -0,0 +1,201 @@
+// Package filter narrows down a list of people based on the query
+// parameters of a request.
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+)
+
+// DateLayout is the format dates are given in.
+const DateLayout = "2006-01-02"
+
+// AsOfParam is the query parameter holding the date ages are
+// calculated as of.
+const AsOfParam = "as_of"
+
+// Filter keeps the people who match every one of its predicates.
+type Filter struct {
+	// AsOf is the date ages are calculated as of. It is the zero
+	// time, meaning today, if the as_of parameter was not given.
+	AsOf  time.Time
+	preds []func(person.Person) bool
+}
+
+// matchAny returns a predicate which is true if the field of a
+// person, ignoring case, satisfies cmp for any of the values.
+func matchAny(field func(person.Person) string, cmp func(string, string) bool, values []string) func(person.Person) bool {
+	return func(p person.Person) bool {
+		got := strings.ToLower(field(p))
+		for _, value := range values {
+			if cmp(got, strings.ToLower(value)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func equal(a, b string) bool {
+	return a == b
+}
+
+func lastName(p person.Person) string      { return p.LastName }
+func firstName(p person.Person) string     { return p.FirstName }
+func gender(p person.Person) string        { return p.Gender }
+func favoriteColor(p person.Person) string { return p.FavoriteColor }
+
+// stringParams are the query parameters which filter on a text field.
+// Giving a parameter more than once keeps people who match any of
+// the values.
+var stringParams = map[string]func([]string) func(person.Person) bool{
+	"last_name": func(values []string) func(person.Person) bool {
+		return matchAny(lastName, equal, values)
+	},
+	"last_name_prefix": func(values []string) func(person.Person) bool {
+		return matchAny(lastName, strings.HasPrefix, values)
+	},
+	"first_name": func(values []string) func(person.Person) bool {
+		return matchAny(firstName, equal, values)
+	},
+	"first_name_prefix": func(values []string) func(person.Person) bool {
+		return matchAny(firstName, strings.HasPrefix, values)
+	},
+	"gender": func(values []string) func(person.Person) bool {
+		return matchAny(gender, equal, values)
+	},
+	"favorite_color": func(values []string) func(person.Person) bool {
+		return matchAny(favoriteColor, equal, values)
+	},
+}
+
+// dateParams are the query parameters which filter on the date of
+// birth. Both bounds are exclusive.
+var dateParams = map[string]func(time.Time) func(person.Person) bool{
+	"born_after": func(t time.Time) func(person.Person) bool {
+		return func(p person.Person) bool { return p.DateOfBirth.After(t) }
+	},
+	"born_before": func(t time.Time) func(person.Person) bool {
+		return func(p person.Person) bool { return p.DateOfBirth.Before(t) }
+	},
+}
+
+// ageParams are the query parameters which filter on age. Both bounds
+// are inclusive.
+var ageParams = map[string]func(age int, asOf time.Time) func(person.Person) bool{
+	"min_age": func(age int, asOf time.Time) func(person.Person) bool {
+		return func(p person.Person) bool { return person.Age(p.DateOfBirth, asOf) >= age }
+	},
+	"max_age": func(age int, asOf time.Time) func(person.Person) bool {
+		return func(p person.Person) bool { return person.Age(p.DateOfBirth, asOf) <= age }
+	},
+}
+
+// Parse builds a Filter from the query parameters. Parameters which
+// are not filters result in an error unless they are listed in
+// otherParams.
+func Parse(q url.Values, otherParams ...string) (Filter, []string) {
+	ignored := map[string]bool{}
+	for _, param := range otherParams {
+		ignored[param] = true
+	}
+	// Sorting the keys keeps the error messages in a predictable
+	// order.
+	keys := []string{}
+	for key := range q {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	f := Filter{}
+	errs := []string{}
+	if values, ok := q[AsOfParam]; ok && !ignored[AsOfParam] {
+		// The age filters need to know this date so it is
+		// parsed before anything else.
+		asOf, err := parseDate(AsOfParam, values)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		f.AsOf = asOf
+		ignored[AsOfParam] = true
+	}
+	// Every age filter uses the same date, even one which runs
+	// past midnight.
+	ageAsOf := f.AsOf
+	if ageAsOf.IsZero() {
+		ageAsOf = time.Now()
+	}
+	for _, key := range keys {
+		values := q[key]
+		if ignored[key] {
+			continue
+		}
+		if newPred, ok := stringParams[key]; ok {
+			f.preds = append(f.preds, newPred(values))
+			continue
+		}
+		if newPred, ok := dateParams[key]; ok {
+			t, err := parseDate(key, values)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			f.preds = append(f.preds, newPred(t))
+			continue
+		}
+		if newPred, ok := ageParams[key]; ok {
+			if len(values) > 1 {
+				errs = append(errs, fmt.Sprintf("%s can only be given once", key))
+				continue
+			}
+			age, err := strconv.Atoi(values[0])
+			if err != nil || age < 0 {
+				errs = append(errs, fmt.Sprintf("%s must be a non-negative integer", key))
+				continue
+			}
+			f.preds = append(f.preds, newPred(age, ageAsOf))
+			continue
+		}
+		errs = append(errs, fmt.Sprintf("unknown query parameter %q", key))
+	}
+	return f, errs
+}
+
+// parseDate parses the value of a query parameter which holds a date
+// and can only be given once.
+func parseDate(key string, values []string) (time.Time, error) {
+	if len(values) > 1 {
+		return time.Time{}, fmt.Errorf("%s can only be given once", key)
+	}
+	t, err := time.Parse(DateLayout, values[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must have the format YYYY-MM-DD", key)
+	}
+	return t, nil
+}
+
+// Match returns true if the person should be kept.
+func (f Filter) Match(p person.Person) bool {
+	for _, pred := range f.preds {
+		if !pred(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the people who should be kept.
+func (f Filter) Apply(ps []person.Person) []person.Person {
+	kept := []person.Person{}
+	for _, p := range ps {
+		if f.Match(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,120 @@
+package filter_test
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/filter"
+	"github.com/lag13/records/internal/person"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantErrs []string
+	}{
+		{
+			name:     "no filters",
+			query:    "",
+			wantErrs: []string{},
+		},
+		{
+			name:     "other parameters are allowed",
+			query:    "limit=10&gender=female",
+			wantErrs: []string{},
+		},
+		{
+			name:  "invalid filters",
+			query: "born_after=1980&born_before=1990-01-01&born_before=1991-01-01&shoe_size=12",
+			wantErrs: []string{
+				"born_after must have the format YYYY-MM-DD",
+				"born_before can only be given once",
+				`unknown query parameter "shoe_size"`,
+			},
+		},
+		{
+			name:  "invalid age filters",
+			query: "min_age=-1&max_age=ten&as_of=2020",
+			wantErrs: []string{
+				"as_of must have the format YYYY-MM-DD",
+				"max_age must be a non-negative integer",
+				"min_age must be a non-negative integer",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, errs := filter.Parse(q, "limit")
+			if got, want := errs, test.wantErrs; !reflect.DeepEqual(got, want) {
+				t.Errorf("got errors %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	ps := []person.Person{
+		{ID: 1, LastName: "Smith", FirstName: "Anne", Gender: "Female", FavoriteColor: "Blue", DateOfBirth: time.Date(1985, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, LastName: "smithers", FirstName: "Waylon", Gender: "Male", FavoriteColor: "blue", DateOfBirth: time.Date(1970, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 3, LastName: "Jones", FirstName: "Beth", Gender: "Female", FavoriteColor: "Red", DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 4, LastName: "Smyth", FirstName: "Carol", Gender: "female", FavoriteColor: "Blue", DateOfBirth: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	tests := []struct {
+		query   string
+		wantIDs []int
+	}{
+		{"", []int{1, 2, 3, 4}},
+		{"gender=female", []int{1, 3, 4}},
+		{"gender=female&favorite_color=blue", []int{1, 4}},
+		{"favorite_color=red&favorite_color=BLUE", []int{1, 2, 3, 4}},
+		{"last_name_prefix=Sm", []int{1, 2, 4}},
+		{"last_name=smith", []int{1}},
+		{"first_name=beth&first_name_prefix=b", []int{3}},
+		{"born_after=1980-01-01", []int{1, 3}},
+		{"born_after=1980-01-01&born_before=1990-01-01", []int{1}},
+		{"gender=female&favorite_color=blue&born_after=1980-01-01&last_name_prefix=Sm", []int{1}},
+		{"min_age=35&as_of=2020-03-01", []int{1, 2, 4}},
+		{"min_age=35&as_of=2020-02-29", []int{2, 4}},
+		{"min_age=31&max_age=40&as_of=2020-03-01", []int{1, 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, errs := filter.Parse(q)
+			if len(errs) > 0 {
+				t.Fatalf("got errors %v", errs)
+			}
+			ids := []int{}
+			for _, p := range f.Apply(ps) {
+				ids = append(ids, p.ID)
+			}
+			if got, want := ids, test.wantIDs; !reflect.DeepEqual(got, want) {
+				t.Errorf("got ids %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseAsOf(t *testing.T) {
+	f, errs := filter.Parse(url.Values{"as_of": {"2020-01-02"}})
+	if len(errs) > 0 {
+		t.Fatalf("got errors %v", errs)
+	}
+	if got, want := f.AsOf, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got as of %v, want %v", got, want)
+	}
+	f, _ = filter.Parse(url.Values{})
+	if !f.AsOf.IsZero() {
+		t.Errorf("got as of %v, want the zero time", f.AsOf)
+	}
+}
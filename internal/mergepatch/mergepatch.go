@@ -0,0 +1,40 @@
+// Package mergepatch implements JSON merge patch as described in RFC
+// 7396: https://tools.ietf.org/html/rfc7396
+package mergepatch
+
+import "encoding/json"
+
+// Apply applies the JSON merge patch to the JSON document and returns
+// the resulting document.
+func Apply(doc []byte, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, p))
+}
+
+// merge is a direct translation of the MergePatch pseudocode in the
+// RFC.
+func merge(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+			continue
+		}
+		targetObj[name] = merge(targetObj[name], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,46 @@
+package mergepatch_test
+
+import (
+	"testing"
+
+	"github.com/lag13/records/internal/mergepatch"
+)
+
+func TestApply(t *testing.T) {
+	// These are the examples from appendix A of the RFC.
+	tests := []struct {
+		doc     string
+		patch   string
+		wantDoc string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.doc+" "+test.patch, func(t *testing.T) {
+			doc, err := mergepatch.Apply([]byte(test.doc), []byte(test.patch))
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got, want := string(doc), test.wantDoc; got != want {
+				t.Errorf("got document %s, want %s", got, want)
+			}
+		})
+	}
+	if _, err := mergepatch.Apply([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Errorf("expected an error when the patch is not JSON")
+	}
+}
@@ -0,0 +1,209 @@
+package multicsv
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Dialect describes a family of delimited formats. A line can be
+// separated by any one of the Delimiters, but only one of them per
+// line, and must have NumFields fields.
+type Dialect struct {
+	// Delimiters are the characters which can separate fields.
+	Delimiters string `json:"delimiters"`
+	// NumFields is how many fields every record has.
+	NumFields int `json:"num_fields"`
+	// Quoting allows fields to be double quoted so they can
+	// contain delimiters (see split).
+	Quoting bool `json:"quoting"`
+	// Trim removes whitespace from around fields.
+	Trim bool `json:"trim"`
+	// CommentPrefix, if not empty, marks lines which start with it
+	// as comments which are skipped.
+	CommentPrefix string `json:"comment_prefix"`
+	// Padded allows the fields of a line to be padded with
+	// whitespace around a delimiter which is not whitespace, like
+	// "A | B | C", even when whitespace is also a delimiter (see
+	// Parse).
+	Padded bool `json:"padded"`
+}
+
+// DefaultDialect is what is used when no other dialect is configured.
+// It accepts the three formats from the original problem: pipe, comma
+// and space separated with five fields.
+var DefaultDialect = Dialect{
+	Delimiters: "|, ",
+	NumFields:  5,
+	Quoting:    true,
+}
+
+// Validate returns an error if the dialect cannot be used.
+func (d Dialect) Validate() error {
+	if d.Delimiters == "" {
+		return fmt.Errorf("there must be at least one delimiter")
+	}
+	if strings.ContainsAny(d.Delimiters, "\r\n") {
+		return fmt.Errorf("a delimiter cannot be a line break")
+	}
+	if d.Quoting && strings.ContainsRune(d.Delimiters, '"') {
+		return fmt.Errorf("a double quote cannot be a delimiter when quoting is on")
+	}
+	if d.Padded && strings.TrimFunc(d.Delimiters, unicode.IsSpace) == "" {
+		return fmt.Errorf("padding needs a delimiter which is not whitespace")
+	}
+	if d.NumFields < 1 {
+		return fmt.Errorf("the number of fields must be at least 1, not %d", d.NumFields)
+	}
+	return nil
+}
+
+// LoadDialect reads a dialect from a JSON file like:
+//
+//	{"delimiters": ";\t", "num_fields": 5, "quoting": true, "trim": true, "comment_prefix": "#", "padded": false}
+//
+// Anything left out is taken from DefaultDialect.
+func LoadDialect(path string) (Dialect, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return Dialect{}, err
+	}
+	// Ignoring the error is fine because we are only reading.
+	defer func() { _ = fh.Close() }()
+	d := DefaultDialect
+	dec := json.NewDecoder(fh)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return Dialect{}, fmt.Errorf("reading dialect %s: %v", path, err)
+	}
+	if err := d.Validate(); err != nil {
+		return Dialect{}, fmt.Errorf("invalid dialect %s: %v", path, err)
+	}
+	return d, nil
+}
+
+// IsComment returns true if the line is a comment.
+func (d Dialect) IsComment(line string) bool {
+	return d.CommentPrefix != "" && strings.HasPrefix(strings.TrimSpace(line), d.CommentPrefix)
+}
+
+// Parse splits one line into its fields. When the dialect is Padded
+// a line is first split on the delimiters which are not whitespace,
+// removing the whitespace around each field. If none of them are in
+// the line, or the line cannot be split that way, then it is split on
+// all the delimiters with runs of whitespace counting as one
+// delimiter, so "Gamgee  Sam" has two fields. This means whitespace
+// inside a field, like "Mary Ann | Smith", is kept when another
+// delimiter is used. Two different delimiters which are not
+// whitespace are still an error.
+func (d Dialect) Parse(s string) ([]string, string) {
+	fields, seps, parseErr := d.split(s)
+	if parseErr != "" {
+		return nil, parseErr
+	}
+	// TODO: I feel like this case is unecessary and a little
+	// strange since you could hypothetically pass in
+	// numFieldsPerRecord = 1 in which case there need not be any
+	// delimiters. Maybe I should not have bothered making
+	// parameters out of delimiters and numFieldsPerRecord
+	if len(seps) == 0 {
+		return nil, "there are no delimiters"
+	}
+	if len(seps) > 1 {
+		sepsStr := fmt.Sprintf("'%c'", seps[0])
+		for _, sep := range seps[1:] {
+			sepsStr = fmt.Sprintf("%s, '%c'", sepsStr, sep)
+		}
+		return nil, fmt.Sprintf("there should only be one type of separator but multiple (%s) were specified", sepsStr)
+	}
+	if numFields := len(fields); numFields != d.NumFields {
+		return nil, fmt.Sprintf("there were %d fields when there should have been %d", numFields, d.NumFields)
+	}
+	return fields, ""
+}
+
+func (d Dialect) split(s string) ([]string, []rune, string) {
+	if d.Padded {
+		solid := strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, d.Delimiters)
+		// If this fails, like for the space separated line
+		// `"Mary Ann" Smith`, the line might still be fine
+		// without padding.
+		fields, seps, parseErr := split(s, solid, d.Quoting, true, false)
+		if parseErr == "" && len(seps) > 0 {
+			return fields, seps, ""
+		}
+	}
+	return split(s, d.Delimiters, d.Quoting, d.Trim, d.Padded)
+}
+
+// Record is the fields of one line along with the number of that
+// line, counting from 1, so errors about the record can point at it
+// even though comments are skipped.
+type Record struct {
+	Line   int
+	Fields []string
+}
+
+// ReadAll reads all records out of the Reader skipping comments.
+func (d Dialect) ReadAll(r io.Reader) ([]Record, []string) {
+	parseErrs := []string{}
+	// TODO: I could see us wanting to ignore empty lines but
+	// bufio.Scanner does NOT ignore empty lines. Keep this in
+	// mind. TODO: Keep in mind that bufio.Scanner has a limited
+	// buffer size:
+	// https://stackoverflow.com/questions/8757389/reading-file-line-by-line-in-go.
+	// I don't imagine it would be a problem especially for this
+	// practice problem but I wanted to think about it a little
+	// more if I tried to make this a more generic package.
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	records := []Record{}
+	for scanner.Scan() {
+		lineNum++
+		if d.IsComment(scanner.Text()) {
+			continue
+		}
+		// TODO: I don't like relying on this Parse() function
+		// because if IT breaks then so does this function
+		// (i.e. they are coupled). I have some ideas on how
+		// to decouple them (in which case this function would
+		// become a more generic "map an arbitrary function
+		// over each line in a file") but I'm not implementing
+		// it in part because I don't think I'll be able to
+		// make something as generic as I want because of Go's
+		// lack of generics. Also I kind of want to move on
+		// with this project and get something submitted so
+		// I'll leave it be. By the way, this is a perfect
+		// example of why I like functional languages, I feel
+		// like they're good about taking an operation which
+		// works on one thing and lifting that operation so it
+		// works on multiple things.
+		record, parseErr := d.Parse(scanner.Text())
+		if parseErr != "" {
+			parseErrs = append(parseErrs, fmt.Sprintf("%d: %s", lineNum, parseErr))
+			continue
+		}
+		records = append(records, Record{Line: lineNum, Fields: record})
+	}
+	// TODO: I'm not sure that I like returning a []string when
+	// something goes wrong with reading the file. Feels like we
+	// should be returning an actual error type, but since we
+	// don't do anything different even IF an error were to happen
+	// this is fine for now.
+	if err := scanner.Err(); err != nil {
+		return nil, []string{fmt.Sprintf("unexpected error reading file: %v", err)}
+	}
+	if len(parseErrs) > 0 {
+		return nil, parseErrs
+	}
+	return records, nil
+}
@@ -4,96 +4,153 @@
 package multicsv
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"strings"
+	"unicode"
 )
 
-func whichSeparatorsUsedInLine(line string, delimiters string) []rune {
-	seenSeps := []rune{}
+// split splits a line into fields on any of the delimiters and
+// returns them along with the delimiters which were used, in the
+// order they appear in delimiters. If quoting is on then, like RFC
+// 4180, a field which starts with a double quote is quoted: it ends
+// at the next double quote, two double quotes in a row stand for one
+// and any delimiters inside it are part of the field. A double quote
+// anywhere else is just part of the field. Quoted fields cannot span
+// lines. If trim is on then whitespace around fields, and around the
+// quotes of quoted fields, is removed. If collapse is on then a run of
+// whitespace delimiters separates two fields just like one of them
+// and whitespace delimiters at the start and end of the line are
+// ignored, so a field cannot be empty when whitespace separates it.
+func split(s string, delimiters string, quoting bool, trim bool, collapse bool) ([]string, []rune, string) {
+	isSpaceSep := func(r rune) bool {
+		return unicode.IsSpace(r) && strings.ContainsRune(delimiters, r)
+	}
+	if collapse {
+		s = strings.TrimFunc(s, isSpaceSep)
+	}
+	rs := []rune(s)
+	fields := []string{}
+	usedSeps := map[rune]bool{}
+	var field strings.Builder
+	fieldStart := true
+	// quoted is true once the current field turned out to be
+	// quoted so it will not be trimmed.
+	quoted := false
+	isPadding := func(r rune) bool {
+		return trim && unicode.IsSpace(r) && !strings.ContainsRune(delimiters, r)
+	}
+	endField := func() {
+		f := field.String()
+		if trim && !quoted {
+			f = strings.TrimSpace(f)
+		}
+		fields = append(fields, f)
+		field.Reset()
+		fieldStart = true
+		quoted = false
+	}
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		if fieldStart && quoting && isPadding(r) {
+			// The field might still turn out to be quoted
+			// so hold on to this in case it is not.
+			field.WriteRune(r)
+			i++
+			continue
+		}
+		if fieldStart && quoting && r == '"' {
+			field.Reset()
+			quoted = true
+			closed := false
+			for i++; i < len(rs); i++ {
+				if rs[i] != '"' {
+					field.WriteRune(rs[i])
+					continue
+				}
+				if i+1 < len(rs) && rs[i+1] == '"' {
+					field.WriteRune('"')
+					i++
+					continue
+				}
+				closed = true
+				i++
+				break
+			}
+			if !closed {
+				return nil, nil, fmt.Sprintf("field %d is missing its closing quote", len(fields)+1)
+			}
+			for i < len(rs) && isPadding(rs[i]) {
+				i++
+			}
+			if i < len(rs) && !strings.ContainsRune(delimiters, rs[i]) {
+				return nil, nil, fmt.Sprintf("field %d has characters after its closing quote", len(fields)+1)
+			}
+			fieldStart = false
+			continue
+		}
+		if collapse && isSpaceSep(r) && i > 0 && isSpaceSep(rs[i-1]) {
+			i++
+			continue
+		}
+		if strings.ContainsRune(delimiters, r) {
+			usedSeps[r] = true
+			endField()
+			i++
+			continue
+		}
+		field.WriteRune(r)
+		fieldStart = false
+		i++
+	}
+	endField()
+	seps := []rune{}
 	for _, sep := range delimiters {
-		if strings.ContainsRune(line, sep) {
-			seenSeps = append(seenSeps, sep)
+		if usedSeps[sep] {
+			seps = append(seps, sep)
 		}
 	}
-	return seenSeps
+	return fields, seps, ""
 }
 
 // Parse converts a string containing a string delimited by something
-// and converts it to a []string
+// and converts it to a []string. Fields can be quoted so they can
+// contain delimiters (see split). It is the same as parsing with a
+// Dialect which has quoting on and nothing else.
 func Parse(s string, delimiters string, numFieldsPerRecord int) ([]string, string) {
-	seps := whichSeparatorsUsedInLine(s, delimiters)
-	// TODO: I feel like this case is unecessary and a little
-	// strange since you could hypothetically pass in
-	// numFieldsPerRecord = 1 in which case there need not be any
-	// delimiters. Maybe I should not have bothered making
-	// parameters out of delimiters and numFieldsPerRecord
-	if len(seps) == 0 {
-		return nil, "there are no delimiters"
-	}
-	if len(seps) > 1 {
-		sepsStr := fmt.Sprintf("'%c'", seps[0])
-		for _, sep := range seps[1:] {
-			sepsStr = fmt.Sprintf("%s, '%c'", sepsStr, sep)
-		}
-		return nil, fmt.Sprintf("there should only be one type of separator but multiple (%s) were specified", sepsStr)
+	return Dialect{Delimiters: delimiters, NumFields: numFieldsPerRecord, Quoting: true}.Parse(s)
+}
+
+// Quote returns the field quoted, if it needs to be, so that Parse
+// reads it back unchanged when any of the delimiters are possible.
+func Quote(field string, delimiters string) string {
+	if !strings.ContainsAny(field, delimiters+"\"\r\n") {
+		return field
 	}
-	fields := strings.Split(s, string(seps[0]))
-	if numFields := len(fields); numFields != numFieldsPerRecord {
-		return nil, fmt.Sprintf("there were %d fields when there should have been %d", numFields, numFieldsPerRecord)
+	return `"` + strings.Replace(field, `"`, `""`, -1) + `"`
+}
+
+// Join joins the fields with sep, quoting them where needed so that
+// Parse reads them back unchanged when any of the delimiters are
+// possible.
+func Join(fields []string, sep rune, delimiters string) string {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = Quote(field, delimiters)
 	}
-	return fields, ""
+	return strings.Join(quoted, string(sep))
 }
 
-// ReadAll reads all records out of the Reader.
+// ReadAll reads all records out of the Reader. It is the same as
+// reading with a Dialect which has quoting on and nothing else.
 func ReadAll(delimiters string, numFieldsPerRecord int, r io.Reader) ([][]string, []string) {
-	parseErrs := []string{}
-	// TODO: I could see us wanting to ignore empty lines but
-	// bufio.Scanner does NOT ignore empty lines. Keep this in
-	// mind. TODO: Keep in mind that bufio.Scanner has a limited
-	// buffer size:
-	// https://stackoverflow.com/questions/8757389/reading-file-line-by-line-in-go.
-	// I don't imagine it would be a problem especially for this
-	// practice problem but I wanted to think about it a little
-	// more if I tried to make this a more generic package.
-	scanner := bufio.NewScanner(r)
-	lineNum := 0
-	records := [][]string{}
-	for scanner.Scan() {
-		lineNum++
-		// TODO: I don't like relying on this Parse() function
-		// because if IT breaks then so does this function
-		// (i.e. they are coupled). I have some ideas on how
-		// to decouple them (in which case this function would
-		// become a more generic "map an arbitrary function
-		// over each line in a file") but I'm not implementing
-		// it in part because I don't think I'll be able to
-		// make something as generic as I want because of Go's
-		// lack of generics. Also I kind of want to move on
-		// with this project and get something submitted so
-		// I'll leave it be. By the way, this is a perfect
-		// example of why I like functional languages, I feel
-		// like they're good about taking an operation which
-		// works on one thing and lifting that operation so it
-		// works on multiple things.
-		record, parseErr := Parse(scanner.Text(), delimiters, numFieldsPerRecord)
-		if parseErr != "" {
-			parseErrs = append(parseErrs, fmt.Sprintf("%d: %s", lineNum, parseErr))
-			continue
-		}
-		records = append(records, record)
-	}
-	// TODO: I'm not sure that I like returning a []string when
-	// something goes wrong with reading the file. Feels like we
-	// should be returning an actual error type, but since we
-	// don't do anything different even IF an error were to happen
-	// this is fine for now.
-	if err := scanner.Err(); err != nil {
-		return nil, []string{fmt.Sprintf("unexpected error reading file: %v", err)}
-	}
-	if len(parseErrs) > 0 {
+	records, parseErrs := Dialect{Delimiters: delimiters, NumFields: numFieldsPerRecord, Quoting: true}.ReadAll(r)
+	if records == nil {
 		return nil, parseErrs
 	}
-	return records, nil
+	fields := make([][]string, len(records))
+	for i, record := range records {
+		fields[i] = record.Fields
+	}
+	return fields, nil
 }
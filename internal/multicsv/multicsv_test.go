@@ -3,6 +3,9 @@ package multicsv_test
 import (
 	"errors"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -58,6 +61,54 @@ func TestParse(t *testing.T) {
 			wantRecord:         []string{"hey", "there", "buddy"},
 			wantParseErr:       "",
 		},
+		{
+			name:               "delimiters inside quotes are data",
+			s:                  `"Smith, Jr.","Mary Ann",female,"blue|green",2001-01-01`,
+			delimiters:         "|, ",
+			numFieldsPerRecord: 5,
+			wantRecord:         []string{"Smith, Jr.", "Mary Ann", "female", "blue|green", "2001-01-01"},
+			wantParseErr:       "",
+		},
+		{
+			name:               "escaped quotes",
+			s:                  `"say ""hi"""|""""|""|o"neil`,
+			delimiters:         "|, ",
+			numFieldsPerRecord: 4,
+			wantRecord:         []string{`say "hi"`, `"`, "", `o"neil`},
+			wantParseErr:       "",
+		},
+		{
+			name:               "quoted delimiters do not count as separators",
+			s:                  `"a b",c`,
+			delimiters:         "|, ",
+			numFieldsPerRecord: 2,
+			wantRecord:         []string{"a b", "c"},
+			wantParseErr:       "",
+		},
+		{
+			name:               "a quoted field is the only field",
+			s:                  `"a,b"`,
+			delimiters:         "|, ",
+			numFieldsPerRecord: 1,
+			wantRecord:         nil,
+			wantParseErr:       "there are no delimiters",
+		},
+		{
+			name:               "missing closing quote",
+			s:                  `a,"b,c`,
+			delimiters:         "|, ",
+			numFieldsPerRecord: 2,
+			wantRecord:         nil,
+			wantParseErr:       "field 2 is missing its closing quote",
+		},
+		{
+			name:               "characters after the closing quote",
+			s:                  `a,"b"c,d`,
+			delimiters:         "|, ",
+			numFieldsPerRecord: 3,
+			wantRecord:         nil,
+			wantParseErr:       "field 2 has characters after its closing quote",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -100,12 +151,12 @@ too|few|seps`),
 			delimiters:         "|, &",
 			numFieldsPerRecord: 5,
 			content: strings.NewReader(`one|two|three|four|five
-6,7,8,,10
+6,7,"8,9",,10
 11 12 13 14 15
 16&17&18&19&20`),
 			wantFields: [][]string{
 				{"one", "two", "three", "four", "five"},
-				{"6", "7", "8", "", "10"},
+				{"6", "7", "8,9", "", "10"},
 				{"11", "12", "13", "14", "15"},
 				{"16", "17", "18", "19", "20"},
 			},
@@ -129,3 +180,168 @@ too|few|seps`),
 		})
 	}
 }
+
+func TestJoin(t *testing.T) {
+	fields := []string{"Smith, Jr.", "Mary Ann", `say "hi"`, "plain", ""}
+	line := multicsv.Join(fields, '|', "|, ")
+	if got, want := line, `"Smith, Jr."|"Mary Ann"|"say ""hi"""|plain|`; got != want {
+		t.Errorf("got line %q, want %q", got, want)
+	}
+	record, parseErr := multicsv.Parse(line, "|, ", len(fields))
+	if parseErr != "" {
+		t.Fatalf("got parse error %q reading the line back", parseErr)
+	}
+	if got, want := record, fields; !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %q, want %q", got, want)
+	}
+}
+
+func TestDialectReadAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		dialect       multicsv.Dialect
+		content       string
+		wantRecords   []multicsv.Record
+		wantParseErrs []string
+	}{
+		{
+			name:    "trimmed fields and comments",
+			dialect: multicsv.Dialect{Delimiters: ";", NumFields: 3, Quoting: true, Trim: true, CommentPrefix: "#"},
+			content: `# last;first;color
+ Baggins ; Frodo ;green
+  # an indented comment
+Gamgee;" Sam ";brown`,
+			wantRecords: []multicsv.Record{
+				{Line: 2, Fields: []string{"Baggins", "Frodo", "green"}},
+				{Line: 4, Fields: []string{"Gamgee", " Sam ", "brown"}},
+			},
+		},
+		{
+			name:    "padded",
+			dialect: multicsv.Dialect{Delimiters: "|, ", NumFields: 3, Quoting: true, Padded: true},
+			content: `Baggins | Frodo | 1968-09-22
+  Mary Ann ,  "Smith, Jr." ,2001-01-01
+Gamgee Sam 1980-04-06
+"Mary Ann" Smith 2001-01-01
+Took |  | 1990-01-01
+  Gamgee  Sam   1980-04-06
+"Mary  Ann"   Smith 2001-01-01`,
+			wantRecords: []multicsv.Record{
+				{Line: 1, Fields: []string{"Baggins", "Frodo", "1968-09-22"}},
+				{Line: 2, Fields: []string{"Mary Ann", "Smith, Jr.", "2001-01-01"}},
+				{Line: 3, Fields: []string{"Gamgee", "Sam", "1980-04-06"}},
+				{Line: 4, Fields: []string{"Mary Ann", "Smith", "2001-01-01"}},
+				{Line: 5, Fields: []string{"Took", "", "1990-01-01"}},
+				{Line: 6, Fields: []string{"Gamgee", "Sam", "1980-04-06"}},
+				{Line: 7, Fields: []string{"Mary  Ann", "Smith", "2001-01-01"}},
+			},
+		},
+		{
+			name:    "padding does not resolve every ambiguity",
+			dialect: multicsv.Dialect{Delimiters: "|, ", NumFields: 3, Quoting: true, Padded: true},
+			content: `Baggins | Frodo, 1968-09-22
+Gamgee, Sam 1980-04-06`,
+			wantParseErrs: []string{
+				"1: there should only be one type of separator but multiple ('|', ',') were specified",
+				"2: there were 2 fields when there should have been 3",
+			},
+		},
+		{
+			name:    "quotes are ordinary characters without quoting",
+			dialect: multicsv.Dialect{Delimiters: ",", NumFields: 3},
+			content: `"a,b",c`,
+			wantRecords: []multicsv.Record{
+				{Line: 1, Fields: []string{`"a`, `b"`, "c"}},
+			},
+		},
+		{
+			name:    "comments are only skipped when there is a prefix",
+			dialect: multicsv.Dialect{Delimiters: ",", NumFields: 2},
+			content: "#a,b\n#c",
+			wantParseErrs: []string{
+				"2: there are no delimiters",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records, parseErrs := test.dialect.ReadAll(strings.NewReader(test.content))
+			if got, want := records, test.wantRecords; !reflect.DeepEqual(got, want) {
+				t.Errorf("got records %+v, want %+v", got, want)
+			}
+			if got, want := parseErrs, test.wantParseErrs; !reflect.DeepEqual(got, want) {
+				t.Errorf("got parse errs %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadDialect(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantDialect multicsv.Dialect
+		wantErr     string
+	}{
+		{
+			name:    "fields left out come from the default",
+			content: `{"delimiters": ";", "trim": true, "comment_prefix": "#"}`,
+			wantDialect: multicsv.Dialect{
+				Delimiters:    ";",
+				NumFields:     multicsv.DefaultDialect.NumFields,
+				Quoting:       true,
+				Trim:          true,
+				CommentPrefix: "#",
+			},
+		},
+		{
+			name:    "unknown fields are rejected",
+			content: `{"delimiter": ";"}`,
+			wantErr: `json: unknown field "delimiter"`,
+		},
+		{
+			name:    "invalid dialect",
+			content: `{"delimiters": "\"", "quoting": true}`,
+			wantErr: "a double quote cannot be a delimiter when quoting is on",
+		},
+		{
+			name:    "padding needs a delimiter which is not whitespace",
+			content: `{"delimiters": " \t", "padded": true}`,
+			wantErr: "padding needs a delimiter which is not whitespace",
+		},
+		{
+			name:    "too few fields",
+			content: `{"num_fields": 0}`,
+			wantErr: "the number of fields must be at least 1, not 0",
+		},
+	}
+	dir, err := ioutil.TempDir("", "multicsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, "dialect.json")
+			if err := ioutil.WriteFile(path, []byte(test.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			d, err := multicsv.LoadDialect(path)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := d, test.wantDialect; !reflect.DeepEqual(got, want) {
+				t.Errorf("got dialect %+v, want %+v", got, want)
+			}
+		})
+	}
+	if _, err := multicsv.LoadDialect(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error loading a missing file")
+	}
+}
@@ -0,0 +1,145 @@
+// Package paginate splits a sorted list of people into pages.
+package paginate
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+// MaxLimit is the largest page that can be requested.
+const MaxLimit = 1000
+
+// The query parameters which control pagination.
+const (
+	LimitParam  = "limit"
+	OffsetParam = "offset"
+	CursorParam = "cursor"
+)
+
+// Request is the page a client asked for.
+type Request struct {
+	// Limit is the maximum number of people on the page. Zero
+	// means there is no limit.
+	Limit int
+	// Offset is the number of people to skip.
+	Offset int
+	// Cursor, if set, was taken from the next link of a previous
+	// page and takes the place of Offset.
+	Cursor string
+}
+
+// Parse reads the pagination query parameters.
+func Parse(q url.Values) (Request, []string) {
+	var r Request
+	errs := []string{}
+	if s := q.Get(LimitParam); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			errs = append(errs, fmt.Sprintf("%s must be an integer between 1 and %d", LimitParam, MaxLimit))
+		}
+		r.Limit = limit
+	}
+	if s := q.Get(OffsetParam); s != "" {
+		offset, err := strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			errs = append(errs, fmt.Sprintf("%s must be a non-negative integer", OffsetParam))
+		}
+		r.Offset = offset
+	}
+	r.Cursor = q.Get(CursorParam)
+	if r.Cursor != "" && q.Get(OffsetParam) != "" {
+		errs = append(errs, fmt.Sprintf("only one of %s and %s can be specified", OffsetParam, CursorParam))
+	}
+	return r, errs
+}
+
+// Requested returns true if the query asked for a page rather than
+// everything.
+func Requested(q url.Values) bool {
+	for _, param := range []string{LimitParam, OffsetParam, CursorParam} {
+		if _, ok := q[param]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// A cursor is the id of the last person on the previous page and the
+// offset of the next page. The id is what is normally used to find
+// where the next page starts so that people added to or removed from
+// earlier pages do not cause people to be skipped or repeated. If
+// that person has since been removed the offset is used instead.
+func encodeCursor(lastID int, nextOffset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", lastID, nextOffset)))
+}
+
+func decodeCursor(cursor string) (int, int, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, false
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	lastID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	nextOffset, err := strconv.Atoi(parts[1])
+	if err != nil || nextOffset < 0 {
+		return 0, 0, false
+	}
+	return lastID, nextOffset, true
+}
+
+// Page returns the requested page of ps along with a description of
+// it. The link to the next page is u with its query parameters
+// changed to point at that page.
+func Page(u *url.URL, ps []person.Person, r Request) ([]person.Person, *response.Pagination, []string) {
+	start := r.Offset
+	if r.Cursor != "" {
+		lastID, nextOffset, ok := decodeCursor(r.Cursor)
+		if !ok {
+			return nil, nil, []string{fmt.Sprintf("%s is invalid", CursorParam)}
+		}
+		start = nextOffset
+		for i, p := range ps {
+			if p.ID == lastID {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if start > len(ps) {
+		start = len(ps)
+	}
+	end := len(ps)
+	if r.Limit > 0 && start+r.Limit < end {
+		end = start + r.Limit
+	}
+	page := ps[start:end]
+	pagination := &response.Pagination{
+		Total:  len(ps),
+		Limit:  r.Limit,
+		Offset: start,
+	}
+	if end < len(ps) && end > start {
+		q := u.Query()
+		if r.Cursor == "" && q.Get(OffsetParam) != "" {
+			q.Set(OffsetParam, strconv.Itoa(end))
+		} else {
+			q.Set(CursorParam, encodeCursor(ps[end-1].ID, end))
+		}
+		next := *u
+		next.RawQuery = q.Encode()
+		pagination.Next = next.RequestURI()
+	}
+	return page, pagination, nil
+}
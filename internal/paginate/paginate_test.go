@@ -0,0 +1,155 @@
+package paginate_test
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/lag13/records/internal/paginate"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantReq   paginate.Request
+		wantErrs  []string
+		requested bool
+	}{
+		{
+			name:     "nothing requested",
+			query:    "",
+			wantErrs: []string{},
+		},
+		{
+			name:      "limit and offset",
+			query:     "limit=10&offset=20",
+			wantReq:   paginate.Request{Limit: 10, Offset: 20},
+			wantErrs:  []string{},
+			requested: true,
+		},
+		{
+			name:      "cursor",
+			query:     "limit=10&cursor=abc",
+			wantReq:   paginate.Request{Limit: 10, Cursor: "abc"},
+			wantErrs:  []string{},
+			requested: true,
+		},
+		{
+			name:    "invalid values",
+			query:   "limit=1001&offset=-1&cursor=abc",
+			wantReq: paginate.Request{Limit: 1001, Offset: -1, Cursor: "abc"},
+			wantErrs: []string{
+				"limit must be an integer between 1 and 1000",
+				"offset must be a non-negative integer",
+				"only one of offset and cursor can be specified",
+			},
+			requested: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, errs := paginate.Parse(q)
+			if got, want := req, test.wantReq; got != want {
+				t.Errorf("got request %+v, want %+v", got, want)
+			}
+			if got, want := errs, test.wantErrs; !reflect.DeepEqual(got, want) {
+				t.Errorf("got errors %q, want %q", got, want)
+			}
+			if got, want := paginate.Requested(q), test.requested; got != want {
+				t.Errorf("got requested %t, want %t", got, want)
+			}
+		})
+	}
+}
+
+func people(ids ...int) []person.Person {
+	ps := []person.Person{}
+	for _, id := range ids {
+		ps = append(ps, person.Person{ID: id})
+	}
+	return ps
+}
+
+// page requests a page from the URL.
+func page(t *testing.T, rawurl string, ps []person.Person) ([]person.Person, *response.Pagination, []string) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, errs := paginate.Parse(u.Query())
+	if len(errs) > 0 {
+		t.Fatalf("got errors parsing %s: %v", rawurl, errs)
+	}
+	return paginate.Page(u, ps, req)
+}
+
+func TestPageWithOffset(t *testing.T) {
+	ps := people(5, 3, 9, 1, 7)
+	got, pagination, errs := page(t, "/records/name?limit=2&offset=1", ps)
+	if len(errs) > 0 {
+		t.Fatalf("got errors %v", errs)
+	}
+	if want := people(3, 9); !reflect.DeepEqual(got, want) {
+		t.Errorf("got page %+v, want %+v", got, want)
+	}
+	wantPagination := response.Pagination{Total: 5, Limit: 2, Offset: 1, Next: "/records/name?limit=2&offset=3"}
+	if got, want := *pagination, wantPagination; got != want {
+		t.Errorf("got pagination %+v, want %+v", got, want)
+	}
+	got, pagination, _ = page(t, pagination.Next, ps)
+	if want := people(1, 7); !reflect.DeepEqual(got, want) {
+		t.Errorf("got page %+v, want %+v", got, want)
+	}
+	if got, want := pagination.Next, ""; got != want {
+		t.Errorf("got next link %q on the last page, want %q", got, want)
+	}
+	got, pagination, _ = page(t, "/records/name?offset=10", ps)
+	if got, want := len(got), 0; got != want {
+		t.Errorf("got %d people past the end, want %d", got, want)
+	}
+	if got, want := pagination.Offset, 5; got != want {
+		t.Errorf("got offset %d past the end, want %d", got, want)
+	}
+}
+
+func TestPageWithCursor(t *testing.T) {
+	ps := people(5, 3, 9, 1, 7)
+	got, pagination, _ := page(t, "/records/name?limit=2", ps)
+	if want := people(5, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("got page %+v, want %+v", got, want)
+	}
+	next := pagination.Next
+	if next == "" {
+		t.Fatal("expected a link to the next page")
+	}
+
+	// Someone is added to the first page, the next page should
+	// still start after the last person we saw.
+	ps = people(2, 5, 3, 9, 1, 7)
+	got, pagination, _ = page(t, next, ps)
+	if want := people(9, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("got page %+v, want %+v", got, want)
+	}
+	if got, want := pagination.Offset, 3; got != want {
+		t.Errorf("got offset %d, want %d", got, want)
+	}
+
+	// The last person we saw is removed, fall back to the offset.
+	ps = people(5, 9, 1, 7)
+	got, _, _ = page(t, next, ps)
+	if want := people(1, 7); !reflect.DeepEqual(got, want) {
+		t.Errorf("got page %+v, want %+v", got, want)
+	}
+
+	_, _, errs := page(t, "/records/name?cursor=garbage", ps)
+	if got, want := errs, []string{"cursor is invalid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got errors %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package person
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/lag13/records/internal/multicsv"
+)
+
+// The fields of a person which are read from a record, in the order
+// they appear in a record without a header.
+const (
+	lastNameColumn = iota
+	firstNameColumn
+	genderColumn
+	favoriteColorColumn
+	dateOfBirthColumn
+	numColumns
+)
+
+// NumFields is how many fields a record of a person has, with or
+// without a header.
+const NumFields = numColumns
+
+// columnDescriptions are how each column is referred to in error
+// messages.
+var columnDescriptions = [numColumns]string{
+	lastNameColumn:      "last name",
+	firstNameColumn:     "first name",
+	genderColumn:        "gender",
+	favoriteColorColumn: "favorite color",
+	dateOfBirthColumn:   "date of birth",
+}
+
+// headerNames are the names a header can give each column. They are
+// compared after normalizeHeaderName so "LastName", "last_name" and
+// "Last Name" are all the same.
+var headerNames = map[string]int{
+	"lastname":       lastNameColumn,
+	"surname":        lastNameColumn,
+	"firstname":      firstNameColumn,
+	"givenname":      firstNameColumn,
+	"gender":         genderColumn,
+	"favoritecolor":  favoriteColorColumn,
+	"favouritecolor": favoriteColorColumn,
+	"color":          favoriteColorColumn,
+	"dateofbirth":    dateOfBirthColumn,
+	"birthdate":      dateOfBirthColumn,
+	"dob":            dateOfBirthColumn,
+}
+
+func normalizeHeaderName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// Columns says which field of a record holds each field of a person.
+// The zero value is the order described by an invalid header and
+// every record parsed with it fails.
+type Columns struct {
+	index [numColumns]int
+}
+
+// DefaultColumns is the order of the fields in a record when there is
+// no header: last name, first name, gender, favorite color and date
+// of birth.
+var DefaultColumns = Columns{index: [numColumns]int{0, 1, 2, 3, 4}}
+
+// ParseHeader reports whether the record is a header, which is true
+// when every field is the name of a column like "LastName" or
+// "date_of_birth", and if so returns the order of the columns it
+// describes. A header which names a column twice or leaves one out is
+// still a header but has errors.
+func ParseHeader(record []string) (Columns, bool, []string) {
+	found := [numColumns]int{}
+	for i := range found {
+		found[i] = -1
+	}
+	parseErrs := []string{}
+	for i, name := range record {
+		col, ok := headerNames[normalizeHeaderName(name)]
+		if !ok {
+			return Columns{}, false, nil
+		}
+		if found[col] != -1 {
+			parseErrs = append(parseErrs, fmt.Sprintf("the header names the %s column twice (fields %d and %d)", columnDescriptions[col], found[col]+1, i+1))
+			continue
+		}
+		found[col] = i
+	}
+	for col, i := range found {
+		if i == -1 {
+			parseErrs = append(parseErrs, fmt.Sprintf("the header has no %s column", columnDescriptions[col]))
+		}
+	}
+	if len(parseErrs) > 0 {
+		return Columns{}, true, parseErrs
+	}
+	return Columns{index: found}, true, nil
+}
+
+// Parse converts a record whose fields are in this order into a
+// Person reading the date of birth with ParseDate. Errors refer to
+// fields by their position in the record.
+func (cols Columns) Parse(record []string, dateFormats []string) (Person, []string) {
+	if cols == (Columns{}) {
+		return Person{}, []string{"the order of the fields is unknown because the header is invalid"}
+	}
+	if len(record) != numColumns {
+		return Person{}, []string{fmt.Sprintf("there were %d fields when there should have been %d", len(record), numColumns)}
+	}
+	field := func(col int) string {
+		return record[cols.index[col]]
+	}
+	parseErrs := []string{}
+	for _, col := range []int{lastNameColumn, firstNameColumn, genderColumn, favoriteColorColumn} {
+		if field(col) != "" {
+			continue
+		}
+		parseErrs = append(parseErrs, fmt.Sprintf("%s (field %d) must be a non-empty string", columnDescriptions[col], cols.index[col]+1))
+	}
+	dob, dateErr := ParseDate(field(dateOfBirthColumn), dateFormats)
+	if dateErr != "" {
+		parseErrs = append(parseErrs, fmt.Sprintf("%s (field %d) %s", columnDescriptions[dateOfBirthColumn], cols.index[dateOfBirthColumn]+1, dateErr))
+	}
+	if len(parseErrs) > 0 {
+		return Person{}, parseErrs
+	}
+	return Person{
+		LastName:      field(lastNameColumn),
+		FirstName:     field(firstNameColumn),
+		Gender:        field(genderColumn),
+		FavoriteColor: field(favoriteColorColumn),
+		DateOfBirth:   dob,
+	}, nil
+}
+
+// LineParser parses the lines of one source, like a file or a request
+// body, into people. A header line gives the order of the columns in
+// the lines after it.
+type LineParser struct {
+	dialect     multicsv.Dialect
+	dateFormats []string
+	cols        Columns
+}
+
+// NewLineParser returns a LineParser for lines in the dialect whose
+// dates of birth are in any of the dateFormats.
+func NewLineParser(d multicsv.Dialect, dateFormats []string) *LineParser {
+	return &LineParser{dialect: d, dateFormats: dateFormats, cols: DefaultColumns}
+}
+
+// ParseLine parses a line into a person. If the line is a header it
+// says so and the person is empty.
+func (lp *LineParser) ParseLine(line string) (Person, bool, []string) {
+	record, parseErr := lp.dialect.Parse(line)
+	if parseErr != "" {
+		return Person{}, false, []string{parseErr}
+	}
+	return lp.ParseRecord(record)
+}
+
+// ParseRecord is like ParseLine for a line which has already been
+// split into its fields.
+func (lp *LineParser) ParseRecord(record []string) (Person, bool, []string) {
+	if cols, isHeader, headerErrs := ParseHeader(record); isHeader {
+		lp.cols = cols
+		return Person{}, true, headerErrs
+	}
+	p, parseErrs := lp.cols.Parse(record, lp.dateFormats)
+	return p, false, parseErrs
+}
@@ -0,0 +1,110 @@
+package person
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the formats a date of birth can be written in, keyed
+// by the name they are referred to by, and the layout time.Parse reads
+// them with. A one letter month or day can also be written with two
+// digits.
+var dateLayouts = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"M/D/YYYY":   "1/2/2006",
+	"D/M/YYYY":   "2/1/2006",
+	"DD.MM.YYYY": "02.01.2006",
+	"YYYYMMDD":   "20060102",
+}
+
+// DefaultDateFormats are the date of birth formats accepted when no
+// others are configured. M/D/YYYY is what the command line application
+// outputs so its output can be read back in.
+var DefaultDateFormats = []string{"YYYY-MM-DD", "M/D/YYYY", "DD.MM.YYYY", "YYYYMMDD"}
+
+// DateFormatsParam is the query parameter which overrides the date
+// formats accepted for the records in a request.
+const DateFormatsParam = "date_formats"
+
+// DateFormatsFromQuery returns the date formats asked for by the
+// date_formats query parameter or the defaults if there is none.
+func DateFormatsFromQuery(q url.Values, defaults []string) ([]string, []string) {
+	values, ok := q[DateFormatsParam]
+	if !ok {
+		return defaults, nil
+	}
+	if len(values) > 1 {
+		return nil, []string{fmt.Sprintf("%s can only be given once", DateFormatsParam)}
+	}
+	formats, err := ParseDateFormats(values[0])
+	if err != nil {
+		return nil, []string{fmt.Sprintf("%s: %v", DateFormatsParam, err)}
+	}
+	return formats, nil
+}
+
+// DateFormatNames returns the names of every date format.
+func DateFormatNames() []string {
+	names := []string{}
+	for name := range dateLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseDateFormats parses a comma separated list of date format names,
+// like "YYYY-MM-DD,D/M/YYYY", keeping their order.
+func ParseDateFormats(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("the list of date formats is empty")
+	}
+	formats := []string{}
+	seen := map[string]bool{}
+	for _, raw := range strings.Split(s, ",") {
+		name := strings.ToUpper(strings.TrimSpace(raw))
+		if _, ok := dateLayouts[name]; !ok {
+			return nil, fmt.Errorf("unknown date format %q, known formats are %s", raw, strings.Join(DateFormatNames(), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("the date format %s is given more than once", name)
+		}
+		seen[name] = true
+		formats = append(formats, name)
+	}
+	return formats, nil
+}
+
+// ParseDate parses a date written in any of the formats, which must be
+// among DateFormatNames and are tried in order. A date which more
+// than one of the formats reads differently, like 03/04/2001 with both
+// M/D/YYYY and D/M/YYYY, is ambiguous and an error. The error message
+// describes what was tried.
+func ParseDate(s string, formats []string) (time.Time, string) {
+	// https://stackoverflow.com/questions/14106541/go-parsing-date-time-strings-which-are-not-standard-formats
+	var date time.Time
+	matched := ""
+	for _, name := range formats {
+		t, err := time.Parse(dateLayouts[name], s)
+		if err != nil {
+			continue
+		}
+		if matched == "" {
+			date, matched = t, name
+			continue
+		}
+		if !t.Equal(date) {
+			return time.Time{}, fmt.Sprintf("is ambiguous, it is %s as %s but %s as %s", date.Format("2006-01-02"), matched, t.Format("2006-01-02"), name)
+		}
+	}
+	if matched == "" {
+		if len(formats) == 1 {
+			return time.Time{}, fmt.Sprintf("must have the format %s", formats[0])
+		}
+		return time.Time{}, fmt.Sprintf("must have one of the formats %s", strings.Join(formats, ", "))
+	}
+	return date, ""
+}
@@ -6,10 +6,14 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/lag13/records/internal/multicsv"
 )
 
-// Person contains data about a person.
+// Person contains data about a person. The ID is assigned when the
+// person is stored and is zero until then.
 type Person struct {
+	ID            int       `json:"id"`
 	LastName      string    `json:"last_name"`
 	FirstName     string    `json:"first_name"`
 	Gender        string    `json:"gender"`
@@ -17,61 +21,106 @@ type Person struct {
 	DateOfBirth   time.Time `json:"birthdate"`
 }
 
-// Parse converts a list of fields into a Person struct. It MUST be
-// passed a slice of at least 5 otherwise it will panic.
+// Parse converts a list of fields, in the order of DefaultColumns,
+// into a Person struct. The date of birth can be in any of the
+// DefaultDateFormats.
 func Parse(fields []string) (Person, []string) {
-	parseErrs := []string{}
-	nonEmptyFieldNames := []string{"last name", "first name", "gender", "favorite color"}
-	for i, fieldName := range nonEmptyFieldNames {
-		if fields[i] != "" {
-			continue
-		}
-		parseErrs = append(parseErrs, fmt.Sprintf("%s (field %d) must be a non-empty string", fieldName, i+1))
-	}
-	// https://stackoverflow.com/questions/14106541/go-parsing-date-time-strings-which-are-not-standard-formats
-	layout := "2006-01-02"
-	dob, err := time.Parse(layout, fields[4])
-	if err != nil {
-		parseErrs = append(parseErrs, "date of birth (field 5) must have the format YYYY-MM-DD")
+	return DefaultColumns.Parse(fields, DefaultDateFormats)
+}
+
+// Birthday returns the date of the person's birthday in the given
+// year. Someone born on February 29th has their birthday on March 1st
+// when the year is not a leap year.
+func Birthday(dob time.Time, year int) time.Time {
+	_, month, day := dob.Date()
+	// time.Date normalizes February 29th of a non leap year to
+	// March 1st which is exactly what we want.
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// NextBirthday returns the date of the first birthday of someone born
+// on dob which is on or after the date from.
+func NextBirthday(dob time.Time, from time.Time) time.Time {
+	year, month, day := from.Date()
+	on := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	next := Birthday(dob, year)
+	if next.Before(on) {
+		next = Birthday(dob, year+1)
 	}
-	if len(parseErrs) > 0 {
-		return Person{}, parseErrs
+	return next
+}
+
+// Age returns how many whole years old someone born on dob is on the
+// date asOf. Only the dates matter, not the time of day.
+func Age(dob time.Time, asOf time.Time) int {
+	year, month, day := asOf.Date()
+	on := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	age := year - dob.Year()
+	if on.Before(Birthday(dob, year)) {
+		age--
 	}
-	return Person{fields[0], fields[1], fields[2], fields[3], dob}, nil
+	return age
 }
 
-// Marshal converts a Person struct into a CSV row. TODO: This only
-// gets used by the command line tool. I get the feeling I should
-// restructure that so more logic exists in something that gets unit
-// tested instead of in main and if that happens this function could
-// live there.
+// Marshal converts a Person struct into a CSV row. Values which
+// contain delimiters are quoted so the row can be read back in. TODO:
+// This only gets used by the command line tool. I get the feeling I
+// should restructure that so more logic exists in something that gets
+// unit tested instead of in main and if that happens this function
+// could live there.
 func Marshal(p Person) string {
-	year, month, day := p.DateOfBirth.Date()
-	return fmt.Sprintf("%s,%s,%s,%s,%02d/%02d/%d", p.LastName, p.FirstName, p.Gender, p.FavoriteColor, month, day, year)
+	return multicsv.Join([]string{p.LastName, p.FirstName, p.Gender, p.FavoriteColor, FormatDate(p.DateOfBirth)}, ',', multicsv.DefaultDialect.Delimiters)
+}
+
+// FormatDate formats a date the way Marshal outputs a date of birth,
+// like 05/15/2003.
+func FormatDate(t time.Time) string {
+	year, month, day := t.Date()
+	return fmt.Sprintf("%02d/%02d/%d", month, day, year)
+}
+
+// LessGenderLastNameAsc reports whether a comes before b when sorting
+// females first then by last name ascending. Both are compared without
+// regard to case.
+func LessGenderLastNameAsc(a Person, b Person) bool {
+	aGender, bGender := strings.ToLower(a.Gender), strings.ToLower(b.Gender)
+	if aGender == bGender {
+		return strings.ToLower(a.LastName) < strings.ToLower(b.LastName)
+	}
+	return aGender < bGender
 }
 
 // SortGenderLastNameAsc sorts a slice of Person structs females first
 // then by last name ascending.
 func SortGenderLastNameAsc(persons []Person) {
 	sort.SliceStable(persons, func(i int, j int) bool {
-		if persons[i].Gender == persons[j].Gender {
-			return strings.ToLower(persons[i].LastName) < strings.ToLower(persons[j].LastName)
-		}
-		return persons[i].Gender < persons[j].Gender
+		return LessGenderLastNameAsc(persons[i], persons[j])
 	})
 }
 
+// LessBirthdateAsc reports whether a comes before b when sorting by
+// birth date.
+func LessBirthdateAsc(a Person, b Person) bool {
+	return a.DateOfBirth.Before(b.DateOfBirth)
+}
+
 // SortBirthdateAsc sorts a slice of Person structs by birth date.
 func SortBirthdateAsc(persons []Person) {
 	sort.SliceStable(persons, func(i int, j int) bool {
-		return persons[i].DateOfBirth.Before(persons[j].DateOfBirth)
+		return LessBirthdateAsc(persons[i], persons[j])
 	})
 }
 
+// LessLastNameDesc reports whether a comes before b when sorting by
+// last name descending.
+func LessLastNameDesc(a Person, b Person) bool {
+	return strings.ToLower(a.LastName) > strings.ToLower(b.LastName)
+}
+
 // SortLastNameDesc sorts a slice of Person structs by last name
 // descending.
 func SortLastNameDesc(persons []Person) {
 	sort.SliceStable(persons, func(i int, j int) bool {
-		return strings.ToLower(persons[i].LastName) > strings.ToLower(persons[j].LastName)
+		return LessLastNameDesc(persons[i], persons[j])
 	})
 }
@@ -6,9 +6,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lag13/records/internal/multicsv"
 	"github.com/lag13/records/internal/person"
 )
 
+func errToStr(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprint(err)
+}
+
 func TestParse(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -29,13 +37,13 @@ func TestParse(t *testing.T) {
 				"first name (field 2) must be a non-empty string",
 				"gender (field 3) must be a non-empty string",
 				"favorite color (field 4) must be a non-empty string",
-				"date of birth (field 5) must have the format YYYY-MM-DD",
+				"date of birth (field 5) must have one of the formats YYYY-MM-DD, M/D/YYYY, DD.MM.YYYY, YYYYMMDD",
 			},
 		},
 		{
 			name:       "valid fields",
 			fields:     []string{"Last", "First", "Gender", "Color", "2006-04-17"},
-			wantPerson: person.Person{"Last", "First", "Gender", "Color", time.Date(2006, 4, 17, 0, 0, 0, 0, time.UTC)},
+			wantPerson: person.Person{LastName: "Last", FirstName: "First", Gender: "Gender", FavoriteColor: "Color", DateOfBirth: time.Date(2006, 4, 17, 0, 0, 0, 0, time.UTC)},
 		},
 	}
 	for _, test := range tests {
@@ -51,19 +59,318 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseDate(t *testing.T) {
+	march4 := time.Date(2001, 3, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		s        string
+		formats  []string
+		wantDate time.Time
+		wantErr  string
+	}{
+		{
+			name:     "YYYY-MM-DD",
+			s:        "2001-03-04",
+			formats:  person.DefaultDateFormats,
+			wantDate: march4,
+		},
+		{
+			name:     "M/D/YYYY",
+			s:        "3/4/2001",
+			formats:  person.DefaultDateFormats,
+			wantDate: march4,
+		},
+		{
+			name:     "M/D/YYYY with leading zeros",
+			s:        "03/04/2001",
+			formats:  person.DefaultDateFormats,
+			wantDate: march4,
+		},
+		{
+			name:     "DD.MM.YYYY",
+			s:        "04.03.2001",
+			formats:  person.DefaultDateFormats,
+			wantDate: march4,
+		},
+		{
+			name:     "YYYYMMDD",
+			s:        "20010304",
+			formats:  person.DefaultDateFormats,
+			wantDate: march4,
+		},
+		{
+			name:    "ambiguous",
+			s:       "03/04/2001",
+			formats: []string{"M/D/YYYY", "D/M/YYYY"},
+			wantErr: "is ambiguous, it is 2001-03-04 as M/D/YYYY but 2001-04-03 as D/M/YYYY",
+		},
+		{
+			name:     "not ambiguous when the formats agree",
+			s:        "04/04/2001",
+			formats:  []string{"M/D/YYYY", "D/M/YYYY"},
+			wantDate: time.Date(2001, 4, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "only one format can read it",
+			s:        "13/04/2001",
+			formats:  []string{"M/D/YYYY", "D/M/YYYY"},
+			wantDate: time.Date(2001, 4, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "not in any format",
+			s:       "2001/03/04",
+			formats: []string{"YYYY-MM-DD", "D/M/YYYY"},
+			wantErr: "must have one of the formats YYYY-MM-DD, D/M/YYYY",
+		},
+		{
+			name:    "not in the only format",
+			s:       "3/4/2001",
+			formats: []string{"YYYY-MM-DD"},
+			wantErr: "must have the format YYYY-MM-DD",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			date, dateErr := person.ParseDate(test.s, test.formats)
+			if got, want := dateErr, test.wantErr; got != want {
+				t.Errorf("got error %q, want %q", got, want)
+			}
+			if got, want := date, test.wantDate; !got.Equal(want) {
+				t.Errorf("got date %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseDateFormats(t *testing.T) {
+	tests := []struct {
+		s           string
+		wantFormats []string
+		wantErr     string
+	}{
+		{
+			s:           "d/m/yyyy, YYYY-MM-DD",
+			wantFormats: []string{"D/M/YYYY", "YYYY-MM-DD"},
+		},
+		{
+			s:       "",
+			wantErr: "the list of date formats is empty",
+		},
+		{
+			s:       "YYYY/MM/DD",
+			wantErr: `unknown date format "YYYY/MM/DD", known formats are D/M/YYYY, DD.MM.YYYY, M/D/YYYY, YYYY-MM-DD, YYYYMMDD`,
+		},
+		{
+			s:       "M/D/YYYY,m/d/yyyy",
+			wantErr: "the date format M/D/YYYY is given more than once",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			formats, err := person.ParseDateFormats(test.s)
+			if got, want := errToStr(err), test.wantErr; got != want {
+				t.Errorf("got error %q, want %q", got, want)
+			}
+			if got, want := formats, test.wantFormats; !reflect.DeepEqual(got, want) {
+				t.Errorf("got formats %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	frodo := person.Person{LastName: "Baggins", FirstName: "Frodo", Gender: "Male", FavoriteColor: "Green", DateOfBirth: time.Date(1968, 9, 22, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name         string
+		header       []string
+		record       []string
+		wantIsHeader bool
+		wantErrs     []string
+		wantPerson   person.Person
+		wantParseErr []string
+	}{
+		{
+			name:         "not a header",
+			header:       []string{"Baggins", "Frodo", "Male", "Green", "1968-09-22"},
+			wantIsHeader: false,
+		},
+		{
+			name:         "reordered columns",
+			header:       []string{"first_name", "Last Name", "DOB", "gender", "FavoriteColor"},
+			record:       []string{"Frodo", "Baggins", "1968-09-22", "Male", "Green"},
+			wantIsHeader: true,
+			wantPerson:   frodo,
+		},
+		{
+			name:         "errors name the field in the record",
+			header:       []string{"LastName", "FirstName", "DateOfBirth", "Gender", "color"},
+			record:       []string{"Baggins", "", "09/22/1968", "Male", "Green"},
+			wantIsHeader: true,
+			wantParseErr: []string{
+				"first name (field 2) must be a non-empty string",
+				"date of birth (field 3) must have the format YYYY-MM-DD",
+			},
+		},
+		{
+			name:         "every field must be a column",
+			header:       []string{"LastName", "FirstName", "DateOfBirth", "Gender", "color"},
+			record:       []string{"Baggins", "Frodo", "1968-09-22", "Male", "Green", "Hobbiton"},
+			wantIsHeader: true,
+			wantParseErr: []string{"there were 6 fields when there should have been 5"},
+		},
+		{
+			name:         "invalid header",
+			header:       []string{"LastName", "FirstName", "surname", "Gender", "color"},
+			record:       []string{"Baggins", "Frodo", "Baggins", "Male", "Green"},
+			wantIsHeader: true,
+			wantErrs: []string{
+				"the header names the last name column twice (fields 1 and 3)",
+				"the header has no date of birth column",
+			},
+			wantParseErr: []string{"the order of the fields is unknown because the header is invalid"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cols, isHeader, errs := person.ParseHeader(test.header)
+			if got, want := isHeader, test.wantIsHeader; got != want {
+				t.Fatalf("got is header %v, want %v", got, want)
+			}
+			if got, want := errs, test.wantErrs; !reflect.DeepEqual(got, want) {
+				t.Errorf("got errors %v, want %v", got, want)
+			}
+			if !isHeader {
+				return
+			}
+			p, parseErrs := cols.Parse(test.record, []string{"YYYY-MM-DD"})
+			if got, want := parseErrs, test.wantParseErr; !reflect.DeepEqual(got, want) {
+				t.Errorf("got parse errors %v, want %v", got, want)
+			}
+			if got, want := p, test.wantPerson; !reflect.DeepEqual(got, want) {
+				t.Errorf("got person %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLineParser(t *testing.T) {
+	frodo := person.Person{LastName: "Baggins", FirstName: "Frodo", Gender: "Male", FavoriteColor: "Green", DateOfBirth: time.Date(1968, 9, 22, 0, 0, 0, 0, time.UTC)}
+	lp := person.NewLineParser(multicsv.DefaultDialect, person.DefaultDateFormats)
+	tests := []struct {
+		line         string
+		wantPerson   person.Person
+		wantIsHeader bool
+		wantErrs     []string
+	}{
+		{
+			line:       "Baggins|Frodo|Male|Green|1968-09-22",
+			wantPerson: frodo,
+		},
+		{
+			line:     "Baggins|Frodo,Male|Green|1968-09-22",
+			wantErrs: []string{"there should only be one type of separator but multiple ('|', ',') were specified"},
+		},
+		{
+			line:         "FirstName,LastName,DateOfBirth,Gender,Color",
+			wantIsHeader: true,
+		},
+		{
+			line:       "Frodo Baggins 9/22/1968 Male Green",
+			wantPerson: frodo,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			p, isHeader, errs := lp.ParseLine(test.line)
+			if got, want := errs, test.wantErrs; !reflect.DeepEqual(got, want) {
+				t.Errorf("got errors %v, want %v", got, want)
+			}
+			if got, want := isHeader, test.wantIsHeader; got != want {
+				t.Errorf("got is header %v, want %v", got, want)
+			}
+			if got, want := p, test.wantPerson; !reflect.DeepEqual(got, want) {
+				t.Errorf("got person %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestAge(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		dob     time.Time
+		asOf    time.Time
+		wantAge int
+	}{
+		{date(2000, time.May, 15), date(2020, time.May, 14), 19},
+		{date(2000, time.May, 15), date(2020, time.May, 15), 20},
+		{date(2000, time.May, 15), date(2020, time.December, 31), 20},
+		{date(2000, time.May, 15), date(2000, time.May, 15), 0},
+		{date(2000, time.February, 29), date(2021, time.February, 28), 20},
+		{date(2000, time.February, 29), date(2021, time.March, 1), 21},
+		{date(2000, time.February, 29), date(2024, time.February, 28), 23},
+		{date(2000, time.February, 29), date(2024, time.February, 29), 24},
+		{date(2000, time.May, 15), time.Date(2020, time.May, 15, 23, 59, 0, 0, time.FixedZone("", -5*60*60)), 20},
+	}
+	for _, test := range tests {
+		if got := person.Age(test.dob, test.asOf); got != test.wantAge {
+			t.Errorf("born %s, as of %s: got age %d, want %d", test.dob.Format("2006-01-02"), test.asOf.Format("2006-01-02"), got, test.wantAge)
+		}
+	}
+}
+
+func TestBirthday(t *testing.T) {
+	leapling := time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC)
+	if got, want := person.Birthday(leapling, 2021), time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := person.Birthday(leapling, 2024), time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestNextBirthday(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		dob      time.Time
+		from     time.Time
+		wantNext time.Time
+	}{
+		{date(1990, time.May, 15), date(2020, time.May, 1), date(2020, time.May, 15)},
+		{date(1990, time.May, 15), date(2020, time.May, 15), date(2020, time.May, 15)},
+		{date(1990, time.May, 15), date(2020, time.May, 16), date(2021, time.May, 15)},
+		{date(1990, time.January, 5), date(2020, time.December, 20), date(2021, time.January, 5)},
+		{date(2000, time.February, 29), date(2021, time.February, 28), date(2021, time.March, 1)},
+		{date(2000, time.February, 29), date(2023, time.March, 2), date(2024, time.February, 29)},
+	}
+	for _, test := range tests {
+		if got := person.NextBirthday(test.dob, test.from); !got.Equal(test.wantNext) {
+			t.Errorf("born %s, from %s: got next birthday %s, want %s", test.dob.Format("2006-01-02"), test.from.Format("2006-01-02"), got.Format("2006-01-02"), test.wantNext.Format("2006-01-02"))
+		}
+	}
+}
+
 func TestMarshal(t *testing.T) {
 	tests := []struct {
 		p       person.Person
 		wantStr string
 	}{
 		{
-			person.Person{"Last", "First", "Gender", "Color", time.Date(2003, time.May, 15, 0, 0, 0, 0, time.UTC)},
+			person.Person{LastName: "Last", FirstName: "First", Gender: "Gender", FavoriteColor: "Color", DateOfBirth: time.Date(2003, time.May, 15, 0, 0, 0, 0, time.UTC)},
 			"Last,First,Gender,Color,05/15/2003",
 		},
 		{
-			person.Person{"Bobbo", "Bob", "Male", "Grey", time.Date(1998, time.December, 2, 0, 0, 0, 0, time.UTC)},
+			person.Person{LastName: "Bobbo", FirstName: "Bob", Gender: "Male", FavoriteColor: "Grey", DateOfBirth: time.Date(1998, time.December, 2, 0, 0, 0, 0, time.UTC)},
 			"Bobbo,Bob,Male,Grey,12/02/1998",
 		},
+		{
+			person.Person{LastName: "Smith, Jr.", FirstName: "Mary Ann", Gender: "Female", FavoriteColor: `"Blue"`, DateOfBirth: time.Date(1998, time.December, 2, 0, 0, 0, 0, time.UTC)},
+			`"Smith, Jr.","Mary Ann",Female,"""Blue""",12/02/1998`,
+		},
 	}
 	for i, test := range tests {
 		t.Run(fmt.Sprintf("running test %d", i), func(t *testing.T) {
@@ -90,10 +397,12 @@ func TestSorts(t *testing.T) {
 				{LastName: "Zed", Gender: "Female"},
 				{LastName: "Tom", Gender: "Male"},
 				{LastName: "Bob", Gender: "Male"},
+				{LastName: "Baker", Gender: "female"},
 			},
 			[]person.Person{
 				{LastName: "Aarons", Gender: "Female"},
 				{LastName: "anderson", Gender: "Female"},
+				{LastName: "Baker", Gender: "female"},
 				{LastName: "Brady", Gender: "Female"},
 				{LastName: "Zed", Gender: "Female"},
 				{LastName: "Aarons", Gender: "Male"},
@@ -2,11 +2,158 @@
 // from most handlers in this repository.
 package response
 
-import "github.com/lag13/records/internal/person"
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
 
-// Structured is a http response with a structured body.
+	"github.com/lag13/records/internal/fields"
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/stats"
+)
+
+// Structured is a http response with a structured body. When
+// encoded as JSON every record includes its age as of AsOf or, if
+// that is the zero time, today. If Fields is not empty then records
+// only include those fields, in that order. Groups is left out when
+// it is nil but an empty map is encoded as an empty object.
 type Structured struct {
-	StatusCode int             `json:"-"`
-	Data       []person.Person `json:"data,omitempty"`
-	Errors     []string        `json:"errors,omitempty"`
+	StatusCode int                        `json:"-"`
+	Header     http.Header                `json:"-"`
+	AsOf       time.Time                  `json:"-"`
+	Fields     []string                   `json:"-"`
+	Data       []person.Person            `json:"data,omitempty"`
+	Results    []LineResult               `json:"results,omitempty"`
+	Pagination *Pagination                `json:"pagination,omitempty"`
+	Stats      *stats.Summary             `json:"stats,omitempty"`
+	Groups     map[string][]person.Person `json:"groups,omitempty"`
+	Errors     []string                   `json:"errors,omitempty"`
+}
+
+// Record is how a person is written in a response body. Besides the
+// stored fields it has fields computed from them.
+type Record struct {
+	person.Person
+	Age int `json:"age"`
+}
+
+// recordFields returns the value of each field of a Record by its
+// name in fields.Record.
+var recordFields = map[string]func(r Record) interface{}{
+	fields.ID:            func(r Record) interface{} { return r.ID },
+	fields.LastName:      func(r Record) interface{} { return r.LastName },
+	fields.FirstName:     func(r Record) interface{} { return r.FirstName },
+	fields.Gender:        func(r Record) interface{} { return r.Gender },
+	fields.FavoriteColor: func(r Record) interface{} { return r.FavoriteColor },
+	fields.Birthdate:     func(r Record) interface{} { return r.DateOfBirth },
+	fields.Age:           func(r Record) interface{} { return r.Age },
+}
+
+// records converts each person to a Record or, if names is not empty,
+// to an object with only those fields of the Record. Names which are
+// not fields of a Record are left out.
+func records(ps []person.Person, asOf time.Time, names []string) []interface{} {
+	if ps == nil {
+		return nil
+	}
+	rs := make([]interface{}, len(ps))
+	for i, p := range ps {
+		r := Record{Person: p, Age: person.Age(p.DateOfBirth, asOf)}
+		if len(names) == 0 {
+			rs[i] = r
+			continue
+		}
+		obj := make(object, 0, len(names))
+		for _, name := range names {
+			if value, ok := recordFields[name]; ok {
+				obj = append(obj, member{Name: name, Value: value(r)})
+			}
+		}
+		rs[i] = obj
+	}
+	return rs
+}
+
+// object is a JSON object which, unlike a map, keeps its members in
+// order.
+type object []member
+
+type member struct {
+	Name  string
+	Value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(m.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalJSON encodes the response body with the records converted
+// to Records.
+func (s Structured) MarshalJSON() ([]byte, error) {
+	asOf := s.AsOf
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	var groups *map[string][]interface{}
+	if s.Groups != nil {
+		m := map[string][]interface{}{}
+		for key, ps := range s.Groups {
+			m[key] = records(ps, asOf, s.Fields)
+		}
+		groups = &m
+	}
+	// structured has the fields of Structured but not its methods
+	// so encoding it does not call MarshalJSON again.
+	type structured Structured
+	return json.Marshal(struct {
+		Data   []interface{}             `json:"data,omitempty"`
+		Groups *map[string][]interface{} `json:"groups,omitempty"`
+		structured
+	}{
+		Data:       records(s.Data, asOf, s.Fields),
+		Groups:     groups,
+		structured: structured(s),
+	})
+}
+
+// Pagination describes which part of a larger list of records is in
+// the response.
+type Pagination struct {
+	// Total is the number of records in the full list.
+	Total  int `json:"total"`
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset"`
+	// Next is the URL of the next page and is empty on the last
+	// page.
+	Next string `json:"next,omitempty"`
+}
+
+// LineResult is what happened to one line of a request body which
+// contained many records. The ID is zero if the line was not added
+// which happens when it has errors or when a different line had
+// errors and nothing was added.
+type LineResult struct {
+	Line   int      `json:"line"`
+	ID     int      `json:"id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
 }
@@ -0,0 +1,88 @@
+package response_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/response"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	leapling := person.Person{ID: 1, LastName: "Leap", FirstName: "Lee", Gender: "female", FavoriteColor: "red", DateOfBirth: time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name     string
+		resp     response.Structured
+		wantBody string
+	}{
+		{
+			name: "records include their age",
+			resp: response.Structured{
+				StatusCode: 200,
+				AsOf:       time.Date(2021, time.February, 28, 0, 0, 0, 0, time.UTC),
+				Data:       []person.Person{leapling},
+				Pagination: &response.Pagination{Total: 1},
+			},
+			wantBody: `{"data":[{"id":1,"last_name":"Leap","first_name":"Lee","gender":"female","favorite_color":"red","birthdate":"2000-02-29T00:00:00Z","age":20}],"pagination":{"total":1,"offset":0}}`,
+		},
+		{
+			name: "grouped records include their age",
+			resp: response.Structured{
+				StatusCode: 200,
+				AsOf:       time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC),
+				Groups:     map[string][]person.Person{"female": {leapling}},
+			},
+			wantBody: `{"groups":{"female":[{"id":1,"last_name":"Leap","first_name":"Lee","gender":"female","favorite_color":"red","birthdate":"2000-02-29T00:00:00Z","age":21}]}}`,
+		},
+		{
+			name: "only the requested fields in the requested order",
+			resp: response.Structured{
+				StatusCode: 200,
+				AsOf:       time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC),
+				Fields:     []string{"age", "last_name", "id"},
+				Data:       []person.Person{leapling},
+				Groups:     map[string][]person.Person{"female": {leapling}},
+			},
+			wantBody: `{"data":[{"age":21,"last_name":"Leap","id":1}],"groups":{"female":[{"age":21,"last_name":"Leap","id":1}]}}`,
+		},
+		{
+			name: "fields records do not have are left out",
+			resp: response.Structured{
+				StatusCode: 200,
+				AsOf:       time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC),
+				Fields:     []string{"shoe_size", "birthdate", "first_name"},
+				Data:       []person.Person{leapling},
+			},
+			wantBody: `{"data":[{"birthdate":"2000-02-29T00:00:00Z","first_name":"Lee"}]}`,
+		},
+		{
+			name: "no groups is an empty object",
+			resp: response.Structured{
+				StatusCode: 200,
+				Groups:     map[string][]person.Person{},
+			},
+			wantBody: `{"groups":{}}`,
+		},
+		{
+			name: "errors",
+			resp: response.Structured{
+				StatusCode: 400,
+				Results:    []response.LineResult{{Line: 1, Errors: []string{"oops"}}},
+				Errors:     []string{"1: oops"},
+			},
+			wantBody: `{"results":[{"line":1,"errors":["oops"]}],"errors":["1: oops"]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, err := json.Marshal(test.resp)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got, want := string(body), test.wantBody; got != want {
+				t.Errorf("got body %s, want %s", got, want)
+			}
+		})
+	}
+}
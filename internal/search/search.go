@@ -0,0 +1,177 @@
+// Package search finds people by name without the searcher needing to
+// know exactly how the name is spelled. Matching ignores case and
+// accents and tolerates a few typos.
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lag13/records/internal/person"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ligatures maps the letters which unicode does not consider to be a
+// plain letter plus accents, so removing accents leaves them alone, to
+// the plain letters they are treated as.
+var ligatures = map[rune]string{
+	'æ': "ae",
+	'œ': "oe",
+	'ß': "ss",
+	'þ': "th",
+	'ð': "d",
+	'đ': "d",
+	'ħ': "h",
+	'ı': "i",
+	'ł': "l",
+	'ø': "o",
+	'ŧ': "t",
+}
+
+// normalize lower cases s, removes accents and splits it into words.
+// Accents are removed by decomposing letters like é into e followed
+// by a combining accent which is then dropped. Anything else which is
+// not a letter or digit, like the apostrophe in al'Thor, is dropped
+// too and hyphens and whitespace separate words.
+func normalize(s string) []string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if plain, ok := ligatures[r]; ok {
+			b.WriteString(plain)
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if r == '-' || unicode.IsSpace(r) {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// distance returns the optimal string alignment distance between a
+// and b i.e. the number of insertions, deletions, substitutions and
+// transpositions of adjacent characters needed to turn a into b.
+func distance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = smallest(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = smallest(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func smallest(first int, rest ...int) int {
+	m := first
+	for _, n := range rest {
+		if n < m {
+			m = n
+		}
+	}
+	return m
+}
+
+// maxTypos is how many typos a word of the given length can have and
+// still match. Short words must be spelled correctly otherwise almost
+// everything would match them.
+func maxTypos(length int) int {
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 6:
+		return 1
+	}
+	return 2
+}
+
+// wordScore returns how well the searched for word matches a word in
+// a name, from 0 (no match) to 1 (exact match).
+func wordScore(query string, word string) float64 {
+	if query == word {
+		return 1
+	}
+	q, w := []rune(query), []rune(word)
+	if len(q) >= 2 && strings.HasPrefix(word, query) {
+		return 0.9
+	}
+	if d := distance(q, w); d <= maxTypos(len(q)) {
+		return 0.8 - 0.1*float64(d)
+	}
+	// Allow typos in a prefix too, like "gandlf" for "Gandalf"
+	// when only part of the name was typed.
+	if len(q) >= 4 && len(w) > len(q) {
+		if d := distance(q, w[:len(q)]); d <= maxTypos(len(q)) {
+			return 0.7 - 0.1*float64(d)
+		}
+	}
+	return 0
+}
+
+// Score returns how well the query matches the first and last name of
+// the person, from 0 (no match) to 1 (exact match). Every word of the
+// query must match one of the words in the names.
+func Score(query string, p person.Person) float64 {
+	queryWords := normalize(query)
+	if len(queryWords) == 0 {
+		return 0
+	}
+	nameWords := append(normalize(p.FirstName), normalize(p.LastName)...)
+	total := 0.0
+	for _, q := range queryWords {
+		best := 0.0
+		for _, w := range nameWords {
+			if s := wordScore(q, w); s > best {
+				best = s
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total / float64(len(queryWords))
+}
+
+// Search returns the people who match the query, best matches first.
+// People who match equally well are kept in their original order.
+func Search(query string, ps []person.Person) []person.Person {
+	type match struct {
+		p     person.Person
+		score float64
+	}
+	matches := []match{}
+	for _, p := range ps {
+		if score := Score(query, p); score > 0 {
+			matches = append(matches, match{p, score})
+		}
+	}
+	sort.SliceStable(matches, func(i int, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	found := make([]person.Person, len(matches))
+	for i, m := range matches {
+		found[i] = m.p
+	}
+	return found
+}
@@ -0,0 +1,140 @@
+package search_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/search"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		p     person.Person
+		want  float64
+	}{
+		{
+			name:  "exact match on the last name",
+			query: "baggins",
+			p:     person.Person{FirstName: "Frodo", LastName: "Baggins"},
+			want:  1,
+		},
+		{
+			name:  "case and accents are ignored",
+			query: "ZOË",
+			p:     person.Person{FirstName: "Zoe", LastName: "Washburne"},
+			want:  1,
+		},
+		{
+			name:  "accents in the name are ignored",
+			query: "eowyn",
+			p:     person.Person{FirstName: "Éowyn", LastName: "Rohan"},
+			want:  1,
+		},
+		{
+			name:  "any accent is ignored",
+			query: "stefan nguyen",
+			p:     person.Person{FirstName: "Ștefan", LastName: "Ngụyễn"},
+			want:  1,
+		},
+		{
+			name:  "letters which are not accented letters are folded",
+			query: "soren larsdottir",
+			p:     person.Person{FirstName: "Søren", LastName: "Larsdóttir"},
+			want:  1,
+		},
+		{
+			name:  "punctuation is ignored",
+			query: "althor",
+			p:     person.Person{FirstName: "Rand", LastName: "al'Thor"},
+			want:  1,
+		},
+		{
+			name:  "prefix match",
+			query: "bag",
+			p:     person.Person{FirstName: "Frodo", LastName: "Baggins"},
+			want:  0.9,
+		},
+		{
+			name:  "one typo",
+			query: "bagins",
+			p:     person.Person{FirstName: "Frodo", LastName: "Baggins"},
+			want:  0.7,
+		},
+		{
+			name:  "transposed letters count as one typo",
+			query: "bgagins",
+			p:     person.Person{FirstName: "Frodo", LastName: "Baggins"},
+			want:  0.7,
+		},
+		{
+			name:  "typo in a prefix",
+			query: "gandl",
+			p:     person.Person{FirstName: "Gandalf", LastName: "Grey"},
+			want:  0.6,
+		},
+		{
+			name:  "too many typos",
+			query: "bxxxins",
+			p:     person.Person{FirstName: "Frodo", LastName: "Baggins"},
+			want:  0,
+		},
+		{
+			name:  "short words need to be spelled correctly",
+			query: "sem",
+			p:     person.Person{FirstName: "Sam", LastName: "Gamgee"},
+			want:  0,
+		},
+		{
+			name:  "every word must match",
+			query: "frodo gamgee",
+			p:     person.Person{FirstName: "Frodo", LastName: "Baggins"},
+			want:  0,
+		},
+		{
+			name:  "the score is averaged over the words",
+			query: "frodo bag",
+			p:     person.Person{FirstName: "Frodo", LastName: "Baggins"},
+			want:  0.95,
+		},
+		{
+			name:  "an empty query matches nothing",
+			query: " ",
+			p:     person.Person{FirstName: "Frodo", LastName: "Baggins"},
+			want:  0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := search.Score(test.query, test.p)
+			if diff := got - test.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("got score %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ps := []person.Person{
+		{ID: 1, FirstName: "Fredo", LastName: "Corleone"},
+		{ID: 2, FirstName: "Samwise", LastName: "Gamgee"},
+		{ID: 3, FirstName: "Frodo", LastName: "Baggins"},
+		{ID: 4, FirstName: "Frodon", LastName: "Brandybuck"},
+		{ID: 5, FirstName: "Frodo", LastName: "Gardner"},
+	}
+	got := search.Search("frodo", ps)
+	want := []person.Person{
+		{ID: 3, FirstName: "Frodo", LastName: "Baggins"},
+		{ID: 5, FirstName: "Frodo", LastName: "Gardner"},
+		{ID: 4, FirstName: "Frodon", LastName: "Brandybuck"},
+		{ID: 1, FirstName: "Fredo", LastName: "Corleone"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := search.Search("gollum", ps); len(got) != 0 {
+		t.Errorf("got %+v, want no matches", got)
+	}
+}
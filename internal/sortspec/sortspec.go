@@ -0,0 +1,137 @@
+// Package sortspec parses sort specifications like
+// "gender,last_name,-birthdate" into a way of sorting people. Each
+// comma separated key is a field name (see fields.Lookup), optionally
+// prefixed with "-" to sort that field in descending order, and later
+// keys break ties in earlier ones.
+package sortspec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lag13/records/internal/fields"
+	"github.com/lag13/records/internal/person"
+)
+
+// compareFn returns a negative number if a sorts before b, a positive
+// number if it sorts after and zero if they are equal.
+type compareFn func(a, b person.Person) int
+
+func compareStrings(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// compareFns are the fields which can be sorted on. Text is compared
+// without regard to case.
+var compareFns = map[string]compareFn{
+	fields.ID: func(a, b person.Person) int {
+		return a.ID - b.ID
+	},
+	fields.LastName: func(a, b person.Person) int {
+		return compareStrings(a.LastName, b.LastName)
+	},
+	fields.FirstName: func(a, b person.Person) int {
+		return compareStrings(a.FirstName, b.FirstName)
+	},
+	fields.Gender: func(a, b person.Person) int {
+		return compareStrings(a.Gender, b.Gender)
+	},
+	fields.FavoriteColor: func(a, b person.Person) int {
+		return compareStrings(a.FavoriteColor, b.FavoriteColor)
+	},
+	fields.Birthdate: func(a, b person.Person) int {
+		switch {
+		case a.DateOfBirth.Before(b.DateOfBirth):
+			return -1
+		case a.DateOfBirth.After(b.DateOfBirth):
+			return 1
+		}
+		return 0
+	},
+}
+
+// FieldNames returns the names of the fields which can be sorted on.
+func FieldNames() []string {
+	names := []string{}
+	for _, name := range fields.Record {
+		if _, ok := compareFns[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+type key struct {
+	field string
+	desc  bool
+}
+
+// Spec is a parsed sort specification.
+type Spec []key
+
+// Parse parses a sort specification.
+func Parse(s string) (Spec, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("the sort specification is empty")
+	}
+	spec := Spec{}
+	for i, rawKey := range strings.Split(s, ",") {
+		k := key{field: strings.TrimSpace(rawKey)}
+		if strings.HasPrefix(k.field, "-") {
+			k.desc = true
+			k.field = k.field[1:]
+		}
+		if k.field == "" {
+			return nil, fmt.Errorf("sort key %d is empty", i+1)
+		}
+		name, ok := fields.Lookup(k.field, FieldNames())
+		if !ok {
+			return nil, fmt.Errorf("unknown sort field %q, allowed fields are %s", k.field, strings.Join(FieldNames(), ", "))
+		}
+		k.field = name
+		spec = append(spec, k)
+	}
+	return spec, nil
+}
+
+// Compare returns a negative number if a sorts before b, a positive
+// number if it sorts after and zero if the spec considers them equal.
+func (s Spec) Compare(a, b person.Person) int {
+	for _, k := range s {
+		c := compareFns[k.field](a, b)
+		if k.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// Less returns true if a sorts before b.
+func (s Spec) Less(a, b person.Person) bool {
+	return s.Compare(a, b) < 0
+}
+
+// Sort sorts the people according to the spec. People the spec
+// considers equal keep their original order.
+func (s Spec) Sort(ps []person.Person) {
+	sort.SliceStable(ps, func(i int, j int) bool {
+		return s.Less(ps[i], ps[j])
+	})
+}
+
+// String returns the canonical form of the spec.
+func (s Spec) String() string {
+	keys := []string{}
+	for _, k := range s {
+		if k.desc {
+			keys = append(keys, "-"+k.field)
+			continue
+		}
+		keys = append(keys, k.field)
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,109 @@
+package sortspec_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/sortspec"
+)
+
+func errToStr(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprint(err)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantSpec string
+		errMsg   string
+	}{
+		{"gender,lastname,-birthdate", "gender,last_name,-birthdate", ""},
+		{" Last_Name , -COLOR,first_name,id", "last_name,-favorite_color,first_name,id", ""},
+		{"", "", "the sort specification is empty"},
+		{"gender,,lastname", "", "sort key 2 is empty"},
+		{"gender,-", "", "sort key 2 is empty"},
+		{"gender,shoesize", "", `unknown sort field "shoesize", allowed fields are id, last_name, first_name, gender, favorite_color, birthdate`},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			spec, err := sortspec.Parse(test.spec)
+			if got, want := errToStr(err), test.errMsg; got != want {
+				t.Errorf("got error %q, want %q", got, want)
+			}
+			if got, want := spec.String(), test.wantSpec; got != want {
+				t.Errorf("got spec %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	date := func(year int) time.Time {
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	ps := []person.Person{
+		{ID: 1, LastName: "Aarons", Gender: "Male", DateOfBirth: date(1990)},
+		{ID: 2, LastName: "Brady", Gender: "Female", DateOfBirth: date(1980)},
+		{ID: 3, LastName: "aarons", Gender: "Female", DateOfBirth: date(1970)},
+		{ID: 4, LastName: "Aarons", Gender: "Female", DateOfBirth: date(1995)},
+		{ID: 5, LastName: "Zed", Gender: "Female", DateOfBirth: date(1980)},
+	}
+	tests := []struct {
+		spec    string
+		wantIDs []int
+	}{
+		{"gender,lastname", []int{3, 4, 2, 5, 1}},
+		{"gender,lastname,-birthdate", []int{4, 3, 2, 5, 1}},
+		{"-lastname", []int{5, 2, 1, 3, 4}},
+		{"birthdate,-id", []int{3, 5, 2, 1, 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			spec, err := sortspec.Parse(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sorted := make([]person.Person, len(ps))
+			copy(sorted, ps)
+			spec.Sort(sorted)
+			ids := []int{}
+			for _, p := range sorted {
+				ids = append(ids, p.ID)
+			}
+			if got, want := ids, test.wantIDs; !reflect.DeepEqual(got, want) {
+				t.Errorf("got ids %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestSortMatchesGenderOrder checks that sorting on gender then last
+// name gives the same order as the /records/gender endpoint.
+func TestSortMatchesGenderOrder(t *testing.T) {
+	ps := []person.Person{
+		{ID: 1, LastName: "Aarons", Gender: "male"},
+		{ID: 2, LastName: "Brady", Gender: "Female"},
+		{ID: 3, LastName: "baker", Gender: "female"},
+		{ID: 4, LastName: "Zed", Gender: "Male"},
+		{ID: 5, LastName: "Adams", Gender: "FEMALE"},
+	}
+	spec, err := sortspec.Parse("gender,last_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]person.Person, len(ps))
+	copy(got, ps)
+	spec.Sort(got)
+	want := make([]person.Person, len(ps))
+	copy(want, ps)
+	person.SortGenderLastNameAsc(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
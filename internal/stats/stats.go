@@ -0,0 +1,75 @@
+// Package stats computes summary statistics over person data.
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+)
+
+// Summary summarizes a list of records. The birthdates and median age
+// are nil when there are no records.
+type Summary struct {
+	Count             int            `json:"count"`
+	ByGender          map[string]int `json:"by_gender"`
+	ByFavoriteColor   map[string]int `json:"by_favorite_color"`
+	OldestBirthdate   *time.Time     `json:"oldest_birthdate"`
+	YoungestBirthdate *time.Time     `json:"youngest_birthdate"`
+	MedianAge         *float64       `json:"median_age"`
+	// ByBirthDecade counts the records born in each decade, keyed
+	// like "1980s".
+	ByBirthDecade map[string]int `json:"by_birth_decade"`
+}
+
+// Compute summarizes the given people with ages calculated as of the
+// given date. Genders and favorite colors are counted case
+// insensitively so the keys of those counts are lower case.
+func Compute(ps []person.Person, asOf time.Time) Summary {
+	stats := Summary{
+		Count:           len(ps),
+		ByGender:        map[string]int{},
+		ByFavoriteColor: map[string]int{},
+		ByBirthDecade:   map[string]int{},
+	}
+	if len(ps) == 0 {
+		return stats
+	}
+	oldest, youngest := ps[0].DateOfBirth, ps[0].DateOfBirth
+	ages := make([]int, len(ps))
+	for i, p := range ps {
+		stats.ByGender[strings.ToLower(p.Gender)]++
+		stats.ByFavoriteColor[strings.ToLower(p.FavoriteColor)]++
+		stats.ByBirthDecade[decade(p.DateOfBirth)]++
+		if p.DateOfBirth.Before(oldest) {
+			oldest = p.DateOfBirth
+		}
+		if p.DateOfBirth.After(youngest) {
+			youngest = p.DateOfBirth
+		}
+		ages[i] = person.Age(p.DateOfBirth, asOf)
+	}
+	median := median(ages)
+	stats.OldestBirthdate = &oldest
+	stats.YoungestBirthdate = &youngest
+	stats.MedianAge = &median
+	return stats
+}
+
+// decade returns the decade the date is in like "1980s".
+func decade(date time.Time) string {
+	return fmt.Sprintf("%ds", date.Year()/10*10)
+}
+
+// median returns the median of a non-empty list of numbers. The list
+// gets sorted.
+func median(ns []int) float64 {
+	sort.Ints(ns)
+	mid := len(ns) / 2
+	if len(ns)%2 == 1 {
+		return float64(ns[mid])
+	}
+	return float64(ns[mid-1]+ns[mid]) / 2
+}
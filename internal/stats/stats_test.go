@@ -0,0 +1,82 @@
+package stats_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/stats"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestCompute(t *testing.T) {
+	asOf := date(2020, time.June, 1)
+	tests := []struct {
+		name      string
+		ps        []person.Person
+		wantStats stats.Summary
+	}{
+		{
+			name: "no records",
+			ps:   nil,
+			wantStats: stats.Summary{
+				ByGender:        map[string]int{},
+				ByFavoriteColor: map[string]int{},
+				ByBirthDecade:   map[string]int{},
+			},
+		},
+		{
+			name: "odd number of records",
+			ps: []person.Person{
+				{Gender: "Female", FavoriteColor: "red", DateOfBirth: date(1985, time.July, 1)},
+				{Gender: "male", FavoriteColor: "Blue", DateOfBirth: date(1990, time.January, 1)},
+				{Gender: "female", FavoriteColor: "blue", DateOfBirth: date(1980, time.June, 1)},
+			},
+			wantStats: stats.Summary{
+				Count:             3,
+				ByGender:          map[string]int{"female": 2, "male": 1},
+				ByFavoriteColor:   map[string]int{"red": 1, "blue": 2},
+				OldestBirthdate:   timePtr(date(1980, time.June, 1)),
+				YoungestBirthdate: timePtr(date(1990, time.January, 1)),
+				MedianAge:         floatPtr(34),
+				ByBirthDecade:     map[string]int{"1980s": 2, "1990s": 1},
+			},
+		},
+		{
+			name: "even number of records",
+			ps: []person.Person{
+				{Gender: "male", FavoriteColor: "green", DateOfBirth: date(2001, time.January, 1)},
+				{Gender: "male", FavoriteColor: "green", DateOfBirth: date(1999, time.December, 31)},
+			},
+			wantStats: stats.Summary{
+				Count:             2,
+				ByGender:          map[string]int{"male": 2},
+				ByFavoriteColor:   map[string]int{"green": 2},
+				OldestBirthdate:   timePtr(date(1999, time.December, 31)),
+				YoungestBirthdate: timePtr(date(2001, time.January, 1)),
+				MedianAge:         floatPtr(19.5),
+				ByBirthDecade:     map[string]int{"1990s": 1, "2000s": 1},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotStats := stats.Compute(test.ps, asOf)
+			if !reflect.DeepEqual(gotStats, test.wantStats) {
+				t.Errorf("got stats %+v, want %+v", gotStats, test.wantStats)
+			}
+		})
+	}
+}
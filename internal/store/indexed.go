@@ -0,0 +1,295 @@
+package store
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lag13/records/internal/person"
+)
+
+// orderLess holds, for every Order, the function which reports
+// whether one person comes before another in that order.
+var orderLess = map[Order]func(a, b person.Person) bool{
+	OrderGenderLastNameAsc: person.LessGenderLastNameAsc,
+	OrderBirthdateAsc:      person.LessBirthdateAsc,
+	OrderLastNameDesc:      person.LessLastNameDesc,
+}
+
+// Indexed is a Store which wraps another Store and keeps its records
+// sorted in each of a number of orders so ListSorted only has to walk
+// an index. Each index is a treap (a binary search tree kept balanced
+// by giving every node a random priority) so a write changes an index
+// in O(log n) time. Nodes are never modified once built, a write
+// copies the nodes on the path to the record which changed, so reads
+// do not wait for writes.
+type Indexed struct {
+	Store
+	// mu is held during writes so the indexes change in the same
+	// order as the wrapped store.
+	mu sync.Mutex
+	// byID holds the current version of every record so the old
+	// version can be found in the indexes when it changes.
+	byID map[int]person.Person
+	less map[Order]func(a, b person.Person) bool
+	// indexes holds the current indexes, a value of type indexes.
+	indexes atomic.Value
+}
+
+// indexes are the roots of the index for each order along with the
+// number of records in every index.
+type indexes struct {
+	roots map[Order]*node
+	n     int
+}
+
+// node is a node of a treap. Every node has a higher priority than
+// its children.
+type node struct {
+	p           person.Person
+	priority    uint32
+	left, right *node
+}
+
+// NewIndexed returns a Store which maintains an index for each of the
+// given orders over the records of s. All writes must go through the
+// returned store for the indexes to stay correct.
+func NewIndexed(s Store, orders ...Order) (*Indexed, error) {
+	ps, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	ix := &Indexed{
+		Store: s,
+		byID:  map[int]person.Person{},
+		less:  map[Order]func(a, b person.Person) bool{},
+	}
+	roots := map[Order]*node{}
+	for _, o := range orders {
+		less, ok := orderLess[o]
+		if !ok {
+			return nil, fmt.Errorf("unknown order %q", o)
+		}
+		ix.less[o] = less
+		roots[o] = nil
+	}
+	ix.indexes.Store(indexes{roots: roots})
+	ix.insert(ps)
+	return ix, nil
+}
+
+// before reports whether a comes before b in an index sorted by less.
+// Ties are broken by id which, since ids increase as records are
+// added, gives the same order as stably sorting the records in the
+// order they were added.
+func before(less func(a, b person.Person) bool, a person.Person, b person.Person) bool {
+	if less(a, b) {
+		return true
+	}
+	if less(b, a) {
+		return false
+	}
+	return a.ID < b.ID
+}
+
+// ListSorted returns every stored person in the given order which
+// must be one of the orders the store was created with.
+func (ix *Indexed) ListSorted(o Order) ([]person.Person, error) {
+	cur := ix.current()
+	root, ok := cur.roots[o]
+	if !ok {
+		return nil, fmt.Errorf("there is no index for the order %q", o)
+	}
+	return appendInOrder(make([]person.Person, 0, cur.n), root), nil
+}
+
+func (ix *Indexed) current() indexes {
+	return ix.indexes.Load().(indexes)
+}
+
+// Add stores a person and adds it to the indexes.
+func (ix *Indexed) Add(p person.Person) (int, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	id, err := ix.Store.Add(p)
+	if err != nil {
+		return 0, err
+	}
+	p.ID = id
+	ix.insert([]person.Person{p})
+	return id, nil
+}
+
+// AddAll stores every person and adds them to the indexes.
+func (ix *Indexed) AddAll(ps []person.Person) ([]int, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ids, err := ix.Store.AddAll(ps)
+	if err != nil {
+		return nil, err
+	}
+	added := make([]person.Person, len(ps))
+	for i, p := range ps {
+		p.ID = ids[i]
+		added[i] = p
+	}
+	ix.insert(added)
+	return ids, nil
+}
+
+// Update replaces the person with the given id and moves it to its
+// new place in the indexes.
+func (ix *Indexed) Update(id int, p person.Person) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.Store.Update(id, p); err != nil {
+		return err
+	}
+	p.ID = id
+	ix.remove(id)
+	ix.insert([]person.Person{p})
+	return nil
+}
+
+// Modify replaces the person with the given id by what fn returns and
+// moves it to its new place in the indexes.
+func (ix *Indexed) Modify(id int, fn func(person.Person) (person.Person, error)) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	var modified person.Person
+	err := ix.Store.Modify(id, func(cur person.Person) (person.Person, error) {
+		p, err := fn(cur)
+		modified = p
+		return p, err
+	})
+	if err != nil {
+		return err
+	}
+	modified.ID = id
+	ix.remove(id)
+	ix.insert([]person.Person{modified})
+	return nil
+}
+
+// Delete removes the person with the given id from the store and the
+// indexes.
+func (ix *Indexed) Delete(id int) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.Store.Delete(id); err != nil {
+		return err
+	}
+	ix.remove(id)
+	return nil
+}
+
+// insert adds the persons to new versions of the indexes. The caller
+// must hold ix.mu.
+func (ix *Indexed) insert(ps []person.Person) {
+	cur := ix.current()
+	roots := map[Order]*node{}
+	for o, root := range cur.roots {
+		less := ix.less[o]
+		for _, p := range ps {
+			root = insertNode(root, p, rand.Uint32(), less)
+		}
+		roots[o] = root
+	}
+	for _, p := range ps {
+		ix.byID[p.ID] = p
+	}
+	ix.indexes.Store(indexes{roots: roots, n: cur.n + len(ps)})
+}
+
+// remove removes the person with the given id from new versions of
+// the indexes. The caller must hold ix.mu.
+func (ix *Indexed) remove(id int) {
+	old, ok := ix.byID[id]
+	if !ok {
+		return
+	}
+	cur := ix.current()
+	roots := map[Order]*node{}
+	for o, root := range cur.roots {
+		roots[o] = removeNode(root, old, ix.less[o])
+	}
+	delete(ix.byID, id)
+	ix.indexes.Store(indexes{roots: roots, n: cur.n - 1})
+}
+
+// appendInOrder appends the persons in the treap rooted at n to ps in
+// sorted order.
+func appendInOrder(ps []person.Person, n *node) []person.Person {
+	for n != nil {
+		ps = appendInOrder(ps, n.left)
+		ps = append(ps, n.p)
+		n = n.right
+	}
+	return ps
+}
+
+// insertNode returns the root of a treap which is the one rooted at n
+// with p added. The nodes of n are not modified.
+func insertNode(n *node, p person.Person, priority uint32, less func(a, b person.Person) bool) *node {
+	if n == nil {
+		return &node{p: p, priority: priority}
+	}
+	c := *n
+	// The child returned by insertNode is always a new node so it
+	// can be modified when rotating it above c.
+	if before(less, p, n.p) {
+		c.left = insertNode(n.left, p, priority, less)
+		if c.left.priority > c.priority {
+			l := c.left
+			c.left, l.right = l.right, &c
+			return l
+		}
+		return &c
+	}
+	c.right = insertNode(n.right, p, priority, less)
+	if c.right.priority > c.priority {
+		r := c.right
+		c.right, r.left = r.left, &c
+		return r
+	}
+	return &c
+}
+
+// removeNode returns the root of a treap which is the one rooted at n
+// without p. The nodes of n are not modified.
+func removeNode(n *node, p person.Person, less func(a, b person.Person) bool) *node {
+	if n == nil {
+		return nil
+	}
+	if n.p.ID == p.ID {
+		return mergeNodes(n.left, n.right)
+	}
+	c := *n
+	if before(less, p, n.p) {
+		c.left = removeNode(n.left, p, less)
+	} else {
+		c.right = removeNode(n.right, p, less)
+	}
+	return &c
+}
+
+// mergeNodes returns the root of a treap holding the persons of both
+// a and b where everything in a comes before everything in b. The
+// nodes of a and b are not modified.
+func mergeNodes(a *node, b *node) *node {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		c := *a
+		c.right = mergeNodes(a.right, b)
+		return &c
+	}
+	c := *b
+	c.left = mergeNodes(a, b.left)
+	return &c
+}
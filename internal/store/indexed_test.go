@@ -0,0 +1,142 @@
+package store_test
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/store"
+)
+
+// TestIndexed makes random changes to an indexed store and checks
+// that the indexes always match sorting the stored records.
+func TestIndexed(t *testing.T) {
+	genders := []string{"female", "male", "Female"}
+	lastNames := []string{"Baggins", "brandybuck", "Took", "took", "Gamgee"}
+	randomPerson := func(r *rand.Rand) person.Person {
+		return person.Person{
+			LastName:    lastNames[r.Intn(len(lastNames))],
+			Gender:      genders[r.Intn(len(genders))],
+			DateOfBirth: time.Date(1960+r.Intn(5), time.January, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	sortFns := map[store.Order]func([]person.Person){
+		store.OrderGenderLastNameAsc: person.SortGenderLastNameAsc,
+		store.OrderBirthdateAsc:      person.SortBirthdateAsc,
+		store.OrderLastNameDesc:      person.SortLastNameDesc,
+	}
+	r := rand.New(rand.NewSource(1))
+	mem := store.NewMemory()
+	for i := 0; i < 10; i++ {
+		if _, err := mem.Add(randomPerson(r)); err != nil {
+			t.Fatalf("got error adding: %v", err)
+		}
+	}
+	s, err := store.NewIndexed(mem, store.OrderGenderLastNameAsc, store.OrderBirthdateAsc, store.OrderLastNameDesc)
+	if err != nil {
+		t.Fatalf("got error creating indexed store: %v", err)
+	}
+	for step := 0; step < 200; step++ {
+		n, err := s.Count()
+		if err != nil {
+			t.Fatalf("got error counting: %v", err)
+		}
+		id := r.Intn(n+5) + 1
+		switch r.Intn(5) {
+		case 0:
+			_, err = s.Add(randomPerson(r))
+		case 1:
+			_, err = s.AddAll([]person.Person{randomPerson(r), randomPerson(r), randomPerson(r)})
+		case 2:
+			err = s.Update(id, randomPerson(r))
+		case 3:
+			err = s.Delete(id)
+		case 4:
+			err = s.Modify(id, func(p person.Person) (person.Person, error) {
+				p.LastName = lastNames[r.Intn(len(lastNames))]
+				return p, nil
+			})
+		}
+		if err != nil && err != store.ErrNotFound {
+			t.Fatalf("step %d: got error: %v", step, err)
+		}
+		ps, err := s.List()
+		if err != nil {
+			t.Fatalf("got error listing: %v", err)
+		}
+		for o, sortFn := range sortFns {
+			want := make([]person.Person, len(ps))
+			copy(want, ps)
+			sortFn(want)
+			got, err := s.ListSorted(o)
+			if err != nil {
+				t.Fatalf("got error listing in order %s: %v", o, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("step %d: got %s index %+v, want %+v", step, o, got, want)
+			}
+		}
+	}
+}
+
+// TestIndexedReadDuringWrites checks that every listing taken while
+// records are being added is sorted and has all the records added
+// before it.
+func TestIndexedReadDuringWrites(t *testing.T) {
+	s, err := store.NewIndexed(store.NewMemory(), store.OrderBirthdateAsc)
+	if err != nil {
+		t.Fatalf("got error creating indexed store: %v", err)
+	}
+	const n = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			p := person.Person{DateOfBirth: time.Date(1900+(i*37)%100, time.January, 1, 0, 0, 0, 0, time.UTC)}
+			if _, err := s.Add(p); err != nil {
+				t.Errorf("got error adding: %v", err)
+				return
+			}
+		}
+	}()
+	last := 0
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+		ps, err := s.ListSorted(store.OrderBirthdateAsc)
+		if err != nil {
+			t.Fatalf("got error listing: %v", err)
+		}
+		if len(ps) < last {
+			t.Fatalf("got %d records after having listed %d", len(ps), last)
+		}
+		last = len(ps)
+		if !sort.SliceIsSorted(ps, func(i int, j int) bool {
+			return person.LessBirthdateAsc(ps[i], ps[j])
+		}) {
+			t.Fatalf("got unsorted records %+v", ps)
+		}
+	}
+	if last != n {
+		t.Errorf("got %d records after every add, want %d", last, n)
+	}
+}
+
+func TestIndexedUnknownOrder(t *testing.T) {
+	if _, err := store.NewIndexed(store.NewMemory(), "shoe-size-asc"); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+	s, err := store.NewIndexed(store.NewMemory(), store.OrderBirthdateAsc)
+	if err != nil {
+		t.Fatalf("got error creating indexed store: %v", err)
+	}
+	if _, err := s.ListSorted(store.OrderLastNameDesc); err == nil {
+		t.Error("expected an error listing in an order which is not indexed")
+	}
+}
@@ -0,0 +1,373 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lag13/records/internal/person"
+)
+
+// LogFileName is the name of the file, inside the data directory,
+// which Log appends to.
+const LogFileName = "records.log"
+
+// Every entry in the log file is a header followed by a JSON encoded
+// logEntry. The header holds the length of the JSON followed by its
+// CRC-32 checksum, both as big endian uint32s.
+const headerSize = 8
+
+const (
+	opAdd    = "add"
+	opAddAll = "add_all"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+type logEntry struct {
+	// Seq increases by one with every entry written and is never
+	// reset, even when the log is compacted, so it can be compared
+	// against the last sequence number covered by a snapshot.
+	Seq    int64          `json:"seq"`
+	Op     string         `json:"op"`
+	ID     int            `json:"id"`
+	Person *person.Person `json:"person,omitempty"`
+	// Persons are added by an add_all entry and get consecutive
+	// ids starting at ID. A single entry is used so either all or
+	// none of them survive a crash.
+	Persons []person.Person `json:"persons,omitempty"`
+}
+
+// Log is a Store which appends every change to a file on disk and
+// fsyncs it before acknowledging the change. When a Log is opened the
+// latest snapshot is loaded and the entries written after it are
+// replayed to rebuild the records which are then served from memory.
+// Compact keeps the replay short by taking a new snapshot and
+// emptying the file.
+type Log struct {
+	dir string
+	mem *Memory
+	// mu serializes writes to the file so entries land in the
+	// same order they are applied to mem.
+	mu        sync.Mutex
+	f         *os.File
+	size      int64
+	seq       int64
+	discarded int64
+}
+
+// OpenLog opens, or creates, the log inside dir and replays it on top
+// of the latest snapshot. If the final entry of the log was only
+// partially written (e.g. the process died in the middle of a write)
+// it is discarded and the file is truncated to the last complete
+// entry. Any other damage to the file results in an error.
+func OpenLog(dir string) (*Log, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, LogFileName)
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if os.IsNotExist(statErr) {
+		// The file's directory entry must also be synced for
+		// the file itself to survive a crash.
+		if err := syncDir(dir); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	snap, err := readSnapshot(dir)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("reading snapshot: %v", err)
+	}
+	l := &Log{dir: dir, mem: NewMemory(), f: f, seq: snap.LastSeq}
+	l.mem.restore(snap)
+	if err := l.replay(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("replaying %s: %v", path, err)
+	}
+	return l, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+	return d.Close()
+}
+
+// replay applies every entry in the file which is not already part of
+// the snapshot to l.mem.
+func (l *Log) replay() error {
+	info, err := l.f.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := info.Size()
+	r := &countingReader{r: l.f}
+	for {
+		start := r.n
+		e, err := readEntry(r, fileSize-start)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF || (err == errChecksum && r.n == fileSize) {
+			l.discarded = fileSize - start
+			if err := l.f.Truncate(start); err != nil {
+				return err
+			}
+			if err := l.f.Sync(); err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("entry at offset %d: %v", start, err)
+		}
+		l.size = r.n
+		// The process can die after a snapshot was taken but
+		// before the log was emptied in which case the
+		// snapshot already includes these entries.
+		if e.Seq <= l.seq {
+			continue
+		}
+		if err := l.mem.apply(e); err != nil {
+			return fmt.Errorf("entry at offset %d: %v", start, err)
+		}
+		l.seq = e.Seq
+	}
+	_, err = l.f.Seek(l.size, io.SeekStart)
+	return err
+}
+
+var errChecksum = errors.New("checksum mismatch")
+
+// readEntry reads the next entry of which there are at most remaining
+// bytes left in the file. A length which goes past the end of the file
+// is treated like a partially written entry, without allocating room
+// for it, because a damaged header could claim up to 4GiB.
+func readEntry(r io.Reader, remaining int64) (logEntry, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return logEntry{}, err
+	}
+	length := int64(binary.BigEndian.Uint32(header[:4]))
+	if length > remaining-headerSize {
+		return logEntry{}, io.ErrUnexpectedEOF
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return logEntry{}, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return logEntry{}, errChecksum
+	}
+	var e logEntry
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return logEntry{}, err
+	}
+	return e, nil
+}
+
+func encodeEntry(e logEntry) []byte {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		// logEntry always marshals so, like in cmd/api, a
+		// panic is acceptable.
+		panic(err)
+	}
+	var buf bytes.Buffer
+	var header [headerSize]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+	buf.Write(header[:])
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Discarded returns the number of bytes of a partially written final
+// entry which were thrown away when the log was opened.
+func (l *Log) Discarded() int64 {
+	return l.discarded
+}
+
+// write assigns the next sequence number to an entry and durably
+// appends it to the file. If anything goes wrong the file is
+// truncated back to its previous size so a failed write does not
+// leave garbage in the middle of the log. The caller must hold l.mu.
+func (l *Log) write(e *logEntry) error {
+	e.Seq = l.seq + 1
+	b := encodeEntry(*e)
+	if _, err := l.f.Write(b); err != nil {
+		return l.rollback(err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return l.rollback(err)
+	}
+	l.size += int64(len(b))
+	l.seq = e.Seq
+	return nil
+}
+
+func (l *Log) rollback(err error) error {
+	if truncErr := l.f.Truncate(l.size); truncErr != nil {
+		return fmt.Errorf("%v (and truncating the log failed: %v)", err, truncErr)
+	}
+	if _, seekErr := l.f.Seek(l.size, io.SeekStart); seekErr != nil {
+		return fmt.Errorf("%v (and seeking in the log failed: %v)", err, seekErr)
+	}
+	return err
+}
+
+// Add durably stores a person and returns the id it was given.
+func (l *Log) Add(p person.Person) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := logEntry{Op: opAdd, ID: l.mem.reserveIDs(1), Person: &p}
+	if err := l.write(&e); err != nil {
+		return 0, err
+	}
+	return e.ID, l.mem.apply(e)
+}
+
+// AddAll durably stores every person, or none of them, and returns
+// the ids they were given.
+func (l *Log) AddAll(ps []person.Person) ([]int, error) {
+	if len(ps) == 0 {
+		return []int{}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := logEntry{Op: opAddAll, ID: l.mem.reserveIDs(len(ps)), Persons: ps}
+	if err := l.write(&e); err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(ps))
+	for i := range ids {
+		ids[i] = e.ID + i
+	}
+	return ids, l.mem.apply(e)
+}
+
+// List returns every stored person in the order they were added.
+func (l *Log) List() ([]person.Person, error) {
+	return l.mem.List()
+}
+
+// Get returns the person with the given id.
+func (l *Log) Get(id int) (person.Person, error) {
+	return l.mem.Get(id)
+}
+
+// Update durably replaces the person with the given id.
+func (l *Log) Update(id int, p person.Person) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.mem.Get(id); err != nil {
+		return err
+	}
+	e := logEntry{Op: opUpdate, ID: id, Person: &p}
+	if err := l.write(&e); err != nil {
+		return err
+	}
+	return l.mem.apply(e)
+}
+
+// Modify durably replaces the person with the given id by what fn
+// returns.
+func (l *Log) Modify(id int, fn func(person.Person) (person.Person, error)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, err := l.mem.Get(id)
+	if err != nil {
+		return err
+	}
+	p, err := fn(cur)
+	if err != nil {
+		return err
+	}
+	e := logEntry{Op: opUpdate, ID: id, Person: &p}
+	if err := l.write(&e); err != nil {
+		return err
+	}
+	return l.mem.apply(e)
+}
+
+// Delete durably removes the person with the given id.
+func (l *Log) Delete(id int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.mem.Get(id); err != nil {
+		return err
+	}
+	if err := l.write(&logEntry{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	return l.mem.Delete(id)
+}
+
+// Compact writes a snapshot of every record and then empties the log
+// so that the next time it is opened there is nothing to replay.
+// Writes are blocked while compacting but reads are not. If the log is
+// already empty then the snapshot is up to date and nothing is done.
+func (l *Log) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.size == 0 {
+		return nil
+	}
+	if err := writeSnapshot(l.dir, l.mem.snapshot(l.seq)); err != nil {
+		return fmt.Errorf("writing snapshot: %v", err)
+	}
+	if err := l.f.Truncate(0); err != nil {
+		return fmt.Errorf("truncating log: %v", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("truncating log: %v", err)
+	}
+	l.size = 0
+	_, err := l.f.Seek(0, io.SeekStart)
+	return err
+}
+
+// Count returns the number of stored persons.
+func (l *Log) Count() (int, error) {
+	return l.mem.Count()
+}
+
+// Close closes the underlying file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
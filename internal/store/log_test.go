@@ -0,0 +1,313 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/store"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func openLog(t *testing.T, dir string) *store.Log {
+	l, err := store.OpenLog(dir)
+	if err != nil {
+		t.Fatalf("got error opening log: %v", err)
+	}
+	return l
+}
+
+func closeLog(t *testing.T, l *store.Log) {
+	if err := l.Close(); err != nil {
+		t.Errorf("got error closing log: %v", err)
+	}
+}
+
+var hobbits = []person.Person{
+	{LastName: "Baggins", FirstName: "Frodo", Gender: "Male", FavoriteColor: "Green", DateOfBirth: time.Date(1900, 9, 22, 0, 0, 0, 0, time.UTC)},
+	{LastName: "Took", FirstName: "Peregrin", Gender: "Male", FavoriteColor: "Yellow", DateOfBirth: time.Date(1932, 6, 9, 0, 0, 0, 0, time.UTC)},
+	{LastName: "Brandybuck", FirstName: "Meriadoc", Gender: "Male", FavoriteColor: "Green", DateOfBirth: time.Date(1914, 8, 12, 0, 0, 0, 0, time.UTC)},
+}
+
+// withID returns the person with the given id.
+func withID(p person.Person, id int) person.Person {
+	p.ID = id
+	return p
+}
+
+func TestLogReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	l := openLog(t, dir)
+	for _, p := range hobbits {
+		if _, err := l.Add(p); err != nil {
+			t.Fatalf("got error adding %+v: %v", p, err)
+		}
+	}
+	if err := l.Delete(2); err != nil {
+		t.Fatalf("got error deleting: %v", err)
+	}
+	if got, want := l.Update(2, hobbits[1]), store.ErrNotFound; got != want {
+		t.Errorf("updating a deleted id got error %v, want %v", got, want)
+	}
+	gollum := person.Person{LastName: "Gollum", FirstName: "Smeagol", Gender: "Male", FavoriteColor: "Grey", DateOfBirth: time.Date(1400, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := l.Update(1, gollum); err != nil {
+		t.Fatalf("got error updating: %v", err)
+	}
+	closeLog(t, l)
+
+	l = openLog(t, dir)
+	defer closeLog(t, l)
+	ps, err := l.List()
+	if err != nil {
+		t.Fatalf("got error listing: %v", err)
+	}
+	if got, want := ps, []person.Person{withID(gollum, 1), withID(hobbits[2], 3)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after replaying got persons %+v, want %+v", got, want)
+	}
+	id, err := l.Add(hobbits[1])
+	if err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	if got, want := id, 4; got != want {
+		t.Errorf("after replaying got id %d, want %d", got, want)
+	}
+}
+
+func TestLogTruncatedFinalEntry(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	l := openLog(t, dir)
+	for _, p := range hobbits {
+		if _, err := l.Add(p); err != nil {
+			t.Fatalf("got error adding %+v: %v", p, err)
+		}
+	}
+	closeLog(t, l)
+	path := filepath.Join(dir, store.LogFileName)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	l = openLog(t, dir)
+	if l.Discarded() == 0 {
+		t.Errorf("expected the partially written entry to be discarded")
+	}
+	ps, err := l.List()
+	if err != nil {
+		t.Fatalf("got error listing: %v", err)
+	}
+	if got, want := ps, []person.Person{withID(hobbits[0], 1), withID(hobbits[1], 2)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got persons %+v, want %+v", got, want)
+	}
+	if _, err := l.Add(hobbits[2]); err != nil {
+		t.Fatalf("got error adding after discarding: %v", err)
+	}
+	closeLog(t, l)
+
+	l = openLog(t, dir)
+	defer closeLog(t, l)
+	if got, want := l.Discarded(), int64(0); got != want {
+		t.Errorf("got %d discarded bytes after the log was repaired, want %d", got, want)
+	}
+	if n, _ := l.Count(); n != len(hobbits) {
+		t.Errorf("got %d persons, want %d", n, len(hobbits))
+	}
+}
+
+func TestLogCorruptedFinalLength(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	l := openLog(t, dir)
+	if _, err := l.Add(hobbits[0]); err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	closeLog(t, l)
+	path := filepath.Join(dir, store.LogFileName)
+	fh, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A header claiming the largest possible entry followed by
+	// a few bytes.
+	if _, err := fh.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, '{', '}'}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fh.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l = openLog(t, dir)
+	defer closeLog(t, l)
+	if got, want := l.Discarded(), int64(10); got != want {
+		t.Errorf("got %d discarded bytes, want %d", got, want)
+	}
+	ps, err := l.List()
+	if err != nil {
+		t.Fatalf("got error listing: %v", err)
+	}
+	if got, want := ps, []person.Person{withID(hobbits[0], 1)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got persons %+v, want %+v", got, want)
+	}
+}
+
+func TestLogCorruptedEntry(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	l := openLog(t, dir)
+	for _, p := range hobbits {
+		if _, err := l.Add(p); err != nil {
+			t.Fatalf("got error adding %+v: %v", p, err)
+		}
+	}
+	closeLog(t, l)
+	path := filepath.Join(dir, store.LogFileName)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a byte inside the payload of the first entry.
+	b[10] ^= 0xff
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.OpenLog(dir); err == nil {
+		t.Errorf("expected an error opening a log with a corrupted entry")
+	}
+}
+
+func TestLogCompact(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	l := openLog(t, dir)
+	for _, p := range hobbits[:2] {
+		if _, err := l.Add(p); err != nil {
+			t.Fatalf("got error adding %+v: %v", p, err)
+		}
+	}
+	if err := l.Delete(1); err != nil {
+		t.Fatalf("got error deleting: %v", err)
+	}
+	path := filepath.Join(dir, store.LogFileName)
+	beforeCompact, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Compact(); err != nil {
+		t.Fatalf("got error compacting: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("expected the log to be empty after compacting, got %+v, %v", info, err)
+	}
+	if _, err := l.Add(hobbits[2]); err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	closeLog(t, l)
+
+	wantPs := []person.Person{withID(hobbits[1], 2), withID(hobbits[2], 3)}
+	l = openLog(t, dir)
+	ps, err := l.List()
+	if err != nil {
+		t.Fatalf("got error listing: %v", err)
+	}
+	if got, want := ps, wantPs; !reflect.DeepEqual(got, want) {
+		t.Errorf("after compacting got persons %+v, want %+v", got, want)
+	}
+	closeLog(t, l)
+
+	// Simulate dying after the snapshot was written but before the
+	// log was emptied. None of those entries should be applied
+	// twice.
+	if err := ioutil.WriteFile(path, beforeCompact, 0644); err != nil {
+		t.Fatal(err)
+	}
+	l = openLog(t, dir)
+	defer closeLog(t, l)
+	ps, err = l.List()
+	if err != nil {
+		t.Fatalf("got error listing: %v", err)
+	}
+	if got, want := ps, []person.Person{withID(hobbits[1], 2)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("when the log was not emptied got persons %+v, want %+v", got, want)
+	}
+	id, err := l.Add(hobbits[0])
+	if err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	if got, want := id, 3; got != want {
+		t.Errorf("got id %d, want %d", got, want)
+	}
+}
+
+func TestLogCompactIdle(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	l := openLog(t, dir)
+	defer closeLog(t, l)
+	if _, err := l.Add(hobbits[0]); err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	if err := l.Compact(); err != nil {
+		t.Fatalf("got error compacting: %v", err)
+	}
+	path := filepath.Join(dir, store.SnapshotFileName)
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Compact(); err != nil {
+		t.Fatalf("got error compacting again: %v", err)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A snapshot is written to a new file which replaces the old
+	// one so the file would be a different one if it was written.
+	if !os.SameFile(before, after) {
+		t.Error("compacting without any writes since the last compaction rewrote the snapshot")
+	}
+}
+
+func TestLogAddAll(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	l := openLog(t, dir)
+	if _, err := l.Add(hobbits[0]); err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	ids, err := l.AddAll(hobbits[1:])
+	if err != nil {
+		t.Fatalf("got error adding all: %v", err)
+	}
+	if got, want := ids, []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got ids %v, want %v", got, want)
+	}
+	closeLog(t, l)
+
+	l = openLog(t, dir)
+	defer closeLog(t, l)
+	ps, err := l.List()
+	if err != nil {
+		t.Fatalf("got error listing: %v", err)
+	}
+	wantPs := []person.Person{withID(hobbits[0], 1), withID(hobbits[1], 2), withID(hobbits[2], 3)}
+	if got, want := ps, wantPs; !reflect.DeepEqual(got, want) {
+		t.Errorf("after replaying got persons %+v, want %+v", got, want)
+	}
+}
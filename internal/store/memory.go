@@ -0,0 +1,178 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lag13/records/internal/person"
+)
+
+// Memory is a Store which keeps everything in memory so all records
+// are lost when the process exits.
+type Memory struct {
+	mu     sync.Mutex
+	ps     []person.Person
+	nextID int
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{nextID: 1}
+}
+
+// Add stores a person and returns the id it was given.
+func (m *Memory) Add(p person.Person) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p.ID = m.nextID
+	m.nextID++
+	m.ps = append(m.ps, p)
+	return p.ID, nil
+}
+
+// AddAll stores every person and returns the ids they were given.
+func (m *Memory) AddAll(ps []person.Person) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]int, len(ps))
+	for i, p := range ps {
+		p.ID = m.nextID
+		m.nextID++
+		m.ps = append(m.ps, p)
+		ids[i] = p.ID
+	}
+	return ids, nil
+}
+
+// List returns every stored person in the order they were added.
+func (m *Memory) List() ([]person.Person, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ps := make([]person.Person, len(m.ps))
+	copy(ps, m.ps)
+	return ps, nil
+}
+
+// Get returns the person with the given id.
+func (m *Memory) Get(id int) (person.Person, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.index(id)
+	if i < 0 {
+		return person.Person{}, ErrNotFound
+	}
+	return m.ps[i], nil
+}
+
+// Update replaces the person with the given id.
+func (m *Memory) Update(id int, p person.Person) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	p.ID = id
+	m.ps[i] = p
+	return nil
+}
+
+// Modify replaces the person with the given id by what fn returns.
+func (m *Memory) Modify(id int, fn func(person.Person) (person.Person, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	p, err := fn(m.ps[i])
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	m.ps[i] = p
+	return nil
+}
+
+// Delete removes the person with the given id.
+func (m *Memory) Delete(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	m.ps = append(m.ps[:i], m.ps[i+1:]...)
+	return nil
+}
+
+// Count returns the number of stored persons.
+func (m *Memory) Count() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.ps), nil
+}
+
+// index returns the position of the person with the given id or -1.
+// Ids are handed out in increasing order and persons are only ever
+// appended so they are sorted by id. The caller must hold m.mu.
+func (m *Memory) index(id int) int {
+	i := sort.Search(len(m.ps), func(i int) bool {
+		return m.ps[i].ID >= id
+	})
+	if i == len(m.ps) || m.ps[i].ID != id {
+		return -1
+	}
+	return i
+}
+
+// reserveIDs hands out n consecutive ids for records which are about
+// to be added with apply and returns the first one.
+func (m *Memory) reserveIDs(n int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextID
+	m.nextID += n
+	return id
+}
+
+// apply performs the change described by a log entry.
+func (m *Memory) apply(e logEntry) error {
+	switch e.Op {
+	case opAdd:
+		if e.Person == nil {
+			return errors.New("add entry is missing the person")
+		}
+		return m.appendWithIDs(e.ID, []person.Person{*e.Person})
+	case opAddAll:
+		return m.appendWithIDs(e.ID, e.Persons)
+	case opUpdate:
+		if e.Person == nil {
+			return errors.New("update entry is missing the person")
+		}
+		return m.Update(e.ID, *e.Person)
+	case opDelete:
+		return m.Delete(e.ID)
+	}
+	return fmt.Errorf("unknown operation %q", e.Op)
+}
+
+// appendWithIDs adds the persons giving them consecutive ids starting
+// at firstID.
+func (m *Memory) appendWithIDs(firstID int, ps []person.Person) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n := len(m.ps); n > 0 && m.ps[n-1].ID >= firstID {
+		return fmt.Errorf("id %d was added out of order", firstID)
+	}
+	for i, p := range ps {
+		p.ID = firstID + i
+		m.ps = append(m.ps, p)
+	}
+	if next := firstID + len(ps); next > m.nextID {
+		m.nextID = next
+	}
+	return nil
+}
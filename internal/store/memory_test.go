@@ -0,0 +1,84 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/store"
+)
+
+func TestMemory(t *testing.T) {
+	s := store.NewMemory()
+	for _, lastName := range []string{"Baggins", "Grey", "Tok"} {
+		if _, err := s.Add(person.Person{LastName: lastName}); err != nil {
+			t.Fatalf("got error adding %q: %v", lastName, err)
+		}
+	}
+	if err := s.Delete(2); err != nil {
+		t.Errorf("got error deleting id 2: %v", err)
+	}
+	if got, want := s.Delete(2), store.ErrNotFound; got != want {
+		t.Errorf("deleting id 2 twice got error %v, want %v", got, want)
+	}
+	if err := s.Update(3, person.Person{ID: 100, LastName: "Took"}); err != nil {
+		t.Errorf("got error updating id 3: %v", err)
+	}
+	if got, want := s.Update(2, person.Person{}), store.ErrNotFound; got != want {
+		t.Errorf("updating a deleted id got error %v, want %v", got, want)
+	}
+	id, err := s.Add(person.Person{LastName: "Gamgee"})
+	if err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	if got, want := id, 4; got != want {
+		t.Errorf("ids should not be reused, got id %d, want %d", got, want)
+	}
+	p, err := s.Get(3)
+	if err != nil {
+		t.Errorf("got error getting id 3: %v", err)
+	}
+	if got, want := p, (person.Person{ID: 3, LastName: "Took"}); got != want {
+		t.Errorf("got person %+v for id 3, want %+v", got, want)
+	}
+	if _, err := s.Get(2); err != store.ErrNotFound {
+		t.Errorf("getting a deleted id got error %v, want %v", err, store.ErrNotFound)
+	}
+	ps, err := s.List()
+	if err != nil {
+		t.Errorf("got error listing: %v", err)
+	}
+	wantPs := []person.Person{{ID: 1, LastName: "Baggins"}, {ID: 3, LastName: "Took"}, {ID: 4, LastName: "Gamgee"}}
+	if got, want := ps, wantPs; !reflect.DeepEqual(got, want) {
+		t.Errorf("got persons %+v, want %+v", got, want)
+	}
+	n, err := s.Count()
+	if err != nil {
+		t.Errorf("got error counting: %v", err)
+	}
+	if got, want := n, 3; got != want {
+		t.Errorf("got count %d, want %d", got, want)
+	}
+}
+
+func TestMemoryAddAll(t *testing.T) {
+	s := store.NewMemory()
+	if _, err := s.Add(person.Person{LastName: "Baggins"}); err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	ids, err := s.AddAll([]person.Person{{LastName: "Took"}, {LastName: "Gamgee"}})
+	if err != nil {
+		t.Fatalf("got error adding all: %v", err)
+	}
+	if got, want := ids, []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got ids %v, want %v", got, want)
+	}
+	ps, err := s.List()
+	if err != nil {
+		t.Fatalf("got error listing: %v", err)
+	}
+	wantPs := []person.Person{{ID: 1, LastName: "Baggins"}, {ID: 2, LastName: "Took"}, {ID: 3, LastName: "Gamgee"}}
+	if got, want := ps, wantPs; !reflect.DeepEqual(got, want) {
+		t.Errorf("got persons %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/lag13/records/internal/person"
+)
+
+// SnapshotFileName is the name of the file, inside the data
+// directory, which holds the most recent snapshot of a Log.
+const SnapshotFileName = "snapshot.json"
+
+// snapshot is the full set of records as of the log entry with
+// sequence number LastSeq.
+type snapshot struct {
+	LastSeq int64           `json:"last_seq"`
+	NextID  int             `json:"next_id"`
+	Records []person.Person `json:"records"`
+}
+
+// snapshot captures the current state of the store.
+func (m *Memory) snapshot(lastSeq int64) snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := snapshot{LastSeq: lastSeq, NextID: m.nextID, Records: make([]person.Person, len(m.ps))}
+	copy(s.Records, m.ps)
+	return s
+}
+
+// restore replaces the state of the store with the snapshot.
+func (m *Memory) restore(s snapshot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID = s.NextID
+	m.ps = s.Records
+}
+
+// readSnapshot reads the snapshot in dir. If there is no snapshot
+// then an empty one is returned.
+func readSnapshot(dir string) (snapshot, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, SnapshotFileName))
+	if os.IsNotExist(err) {
+		return snapshot{NextID: 1}, nil
+	}
+	if err != nil {
+		return snapshot{}, err
+	}
+	var s snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return snapshot{}, err
+	}
+	return s, nil
+}
+
+// writeSnapshot atomically replaces the snapshot in dir. The snapshot
+// is written to a temporary file which is renamed over the old one so
+// a crash part way through leaves the previous snapshot intact.
+func writeSnapshot(dir string, s snapshot) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	tmp, err := ioutil.TempFile(dir, SnapshotFileName+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, SnapshotFileName)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+	// Registers the pure Go "sqlite" database/sql driver.
+	"modernc.org/sqlite"
+)
+
+// SQLiteFileName is the name of the database file, inside the data
+// directory, which SQLite uses.
+const SQLiteFileName = "records.db"
+
+// lowerCollation compares text the way the person package sorts last
+// names, after strings.ToLower. SQLite's own NOCASE only folds ASCII
+// letters so it would order names like Ölsen and ölsen differently
+// from the other stores.
+const lowerCollation = "GO_LOWER"
+
+func init() {
+	sqlite.MustRegisterCollationUtf8(lowerCollation, func(left, right string) int {
+		return strings.Compare(strings.ToLower(left), strings.ToLower(right))
+	})
+}
+
+// The indexes mirror the orders a Sorter can return records in so
+// those queries walk an index instead of sorting every row. The id is
+// included as the final column because records which tie are
+// returned in the order they were added, just like the stable sorts
+// in the person package. The indexes from before lowerCollation
+// existed, which used NOCASE, are dropped.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS persons (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	last_name      TEXT NOT NULL,
+	first_name     TEXT NOT NULL,
+	gender         TEXT NOT NULL,
+	favorite_color TEXT NOT NULL,
+	birthdate      TEXT NOT NULL
+);
+DROP INDEX IF EXISTS persons_gender_last_name;
+DROP INDEX IF EXISTS persons_last_name_desc;
+DROP INDEX IF EXISTS persons_gender_last_name_lower;
+CREATE INDEX IF NOT EXISTS persons_gender_lower_last_name_lower ON persons (gender COLLATE ` + lowerCollation + `, last_name COLLATE ` + lowerCollation + `, id);
+CREATE INDEX IF NOT EXISTS persons_birthdate ON persons (birthdate, id);
+CREATE INDEX IF NOT EXISTS persons_last_name_lower_desc ON persons (last_name COLLATE ` + lowerCollation + ` DESC, id);
+`
+
+// Birthdates are stored as text in this layout which sorts the same
+// way as the dates themselves.
+const sqliteDateLayout = "2006-01-02"
+
+const insertPerson = "INSERT INTO persons (last_name, first_name, gender, favorite_color, birthdate) VALUES (?, ?, ?, ?, ?)"
+
+var sqliteOrderBy = map[Order]string{
+	OrderGenderLastNameAsc: "gender COLLATE " + lowerCollation + ", last_name COLLATE " + lowerCollation + ", id",
+	OrderBirthdateAsc:      "birthdate, id",
+	OrderLastNameDesc:      "last_name COLLATE " + lowerCollation + " DESC, id",
+}
+
+// SQLite is a Store backed by an embedded SQLite database.
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens, or creates, the SQLite database at path.
+func OpenSQLite(path string) (*SQLite, error) {
+	// Transactions take the write lock when they begin, instead of
+	// when they first write, so the read in Modify cannot be
+	// invalidated by another writer before its update.
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("creating schema: %v", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Add stores a person and returns the id it was given.
+func (s *SQLite) Add(p person.Person) (int, error) {
+	res, err := s.db.Exec(insertPerson, p.LastName, p.FirstName, p.Gender, p.FavoriteColor, p.DateOfBirth.Format(sqliteDateLayout))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// AddAll stores every person, in a single transaction, and returns
+// the ids they were given.
+func (s *SQLite) AddAll(ps []person.Person) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(ps))
+	for i, p := range ps {
+		res, err := tx.Exec(insertPerson, p.LastName, p.FirstName, p.Gender, p.FavoriteColor, p.DateOfBirth.Format(sqliteDateLayout))
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		ids[i] = int(id)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// List returns every stored person in the order they were added.
+func (s *SQLite) List() ([]person.Person, error) {
+	return s.query("ORDER BY id")
+}
+
+// ListSorted returns every stored person in the given order.
+func (s *SQLite) ListSorted(o Order) ([]person.Person, error) {
+	orderBy, ok := sqliteOrderBy[o]
+	if !ok {
+		return nil, fmt.Errorf("unknown order %q", o)
+	}
+	return s.query("ORDER BY " + orderBy)
+}
+
+// query selects every person, the clause is appended to the query.
+func (s *SQLite) query(clause string) ([]person.Person, error) {
+	rows, err := s.db.Query("SELECT id, last_name, first_name, gender, favorite_color, birthdate FROM persons " + clause)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	ps := []person.Person{}
+	for rows.Next() {
+		p, err := scanPerson(rows)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+	return ps, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPerson(row scanner) (person.Person, error) {
+	var p person.Person
+	var dob string
+	if err := row.Scan(&p.ID, &p.LastName, &p.FirstName, &p.Gender, &p.FavoriteColor, &dob); err != nil {
+		return person.Person{}, err
+	}
+	t, err := time.Parse(sqliteDateLayout, dob)
+	if err != nil {
+		return person.Person{}, err
+	}
+	p.DateOfBirth = t
+	return p, nil
+}
+
+// Get returns the person with the given id.
+func (s *SQLite) Get(id int) (person.Person, error) {
+	row := s.db.QueryRow("SELECT id, last_name, first_name, gender, favorite_color, birthdate FROM persons WHERE id = ?", id)
+	p, err := scanPerson(row)
+	if err == sql.ErrNoRows {
+		return person.Person{}, ErrNotFound
+	}
+	return p, err
+}
+
+// Update replaces the person with the given id.
+func (s *SQLite) Update(id int, p person.Person) error {
+	res, err := s.db.Exec(
+		"UPDATE persons SET last_name = ?, first_name = ?, gender = ?, favorite_color = ?, birthdate = ? WHERE id = ?",
+		p.LastName, p.FirstName, p.Gender, p.FavoriteColor, p.DateOfBirth.Format(sqliteDateLayout), id,
+	)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// Modify replaces the person with the given id by what fn returns in
+// a single transaction.
+func (s *SQLite) Modify(id int, fn func(person.Person) (person.Person, error)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	row := tx.QueryRow("SELECT id, last_name, first_name, gender, favorite_color, birthdate FROM persons WHERE id = ?", id)
+	cur, err := scanPerson(row)
+	if err == sql.ErrNoRows {
+		err = ErrNotFound
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	p, err := fn(cur)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(
+		"UPDATE persons SET last_name = ?, first_name = ?, gender = ?, favorite_color = ?, birthdate = ? WHERE id = ?",
+		p.LastName, p.FirstName, p.Gender, p.FavoriteColor, p.DateOfBirth.Format(sqliteDateLayout), id,
+	); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes the person with the given id.
+func (s *SQLite) Delete(id int) error {
+	res, err := s.db.Exec("DELETE FROM persons WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+// expectOneRow returns ErrNotFound if the statement did not affect
+// any rows.
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Count returns the number of stored persons.
+func (s *SQLite) Count() (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM persons").Scan(&n)
+	return n, err
+}
+
+// Close closes the database.
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
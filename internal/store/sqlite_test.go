@@ -0,0 +1,130 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/store"
+)
+
+func TestSQLite(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s, err := store.OpenSQLite(filepath.Join(dir, store.SQLiteFileName))
+	if err != nil {
+		t.Fatalf("got error opening database: %v", err)
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("got error closing database: %v", err)
+		}
+	}()
+	if _, err := s.Add(hobbits[0]); err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	ids, err := s.AddAll(hobbits[1:])
+	if err != nil {
+		t.Fatalf("got error adding all: %v", err)
+	}
+	if got, want := ids, []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got ids %v, want %v", got, want)
+	}
+	if err := s.Delete(2); err != nil {
+		t.Errorf("got error deleting id 2: %v", err)
+	}
+	if got, want := s.Delete(2), store.ErrNotFound; got != want {
+		t.Errorf("deleting id 2 twice got error %v, want %v", got, want)
+	}
+	if _, err := s.Get(2); err != store.ErrNotFound {
+		t.Errorf("getting a deleted id got error %v, want %v", err, store.ErrNotFound)
+	}
+	p, err := s.Get(3)
+	if err != nil {
+		t.Errorf("got error getting id 3: %v", err)
+	}
+	if got, want := p, withID(hobbits[2], 3); got != want {
+		t.Errorf("got person %+v for id 3, want %+v", got, want)
+	}
+	if got, want := s.Update(2, hobbits[0]), store.ErrNotFound; got != want {
+		t.Errorf("updating a deleted id got error %v, want %v", got, want)
+	}
+	if err := s.Update(1, withID(hobbits[1], 100)); err != nil {
+		t.Errorf("got error updating id 1: %v", err)
+	}
+	id, err := s.Add(hobbits[1])
+	if err != nil {
+		t.Fatalf("got error adding: %v", err)
+	}
+	if got, want := id, 4; got != want {
+		t.Errorf("ids should not be reused, got id %d, want %d", got, want)
+	}
+	ps, err := s.List()
+	if err != nil {
+		t.Errorf("got error listing: %v", err)
+	}
+	if got, want := ps, []person.Person{withID(hobbits[1], 1), withID(hobbits[2], 3), withID(hobbits[1], 4)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got persons %+v, want %+v", got, want)
+	}
+	n, err := s.Count()
+	if err != nil {
+		t.Errorf("got error counting: %v", err)
+	}
+	if got, want := n, 3; got != want {
+		t.Errorf("got count %d, want %d", got, want)
+	}
+}
+
+func TestSQLiteListSorted(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s, err := store.OpenSQLite(filepath.Join(dir, store.SQLiteFileName))
+	if err != nil {
+		t.Fatalf("got error opening database: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+	ps := []person.Person{
+		{LastName: "Aarons", Gender: "Male", DateOfBirth: time.Date(1998, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		{LastName: "Brady", Gender: "Female", DateOfBirth: time.Date(1900, time.December, 2, 0, 0, 0, 0, time.UTC)},
+		{LastName: "Aarons", Gender: "Female", DateOfBirth: time.Date(2000, time.December, 2, 0, 0, 0, 0, time.UTC)},
+		{LastName: "anderson", Gender: "Female", DateOfBirth: time.Date(1998, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		{LastName: "Zed", Gender: "Female", DateOfBirth: time.Date(1100, time.April, 19, 0, 0, 0, 0, time.UTC)},
+		{LastName: "aarons", Gender: "Male", DateOfBirth: time.Date(1998, time.December, 2, 0, 0, 0, 0, time.UTC)},
+		{LastName: "Bob", Gender: "Male", DateOfBirth: time.Date(1998, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		{LastName: "Baker", Gender: "female", DateOfBirth: time.Date(1960, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		// NOCASE would not treat these as equal.
+		{LastName: "Ölsen", Gender: "Male", DateOfBirth: time.Date(1970, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		{LastName: "ölsen", Gender: "Male", DateOfBirth: time.Date(1971, time.May, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for i, p := range ps {
+		if _, err := s.Add(p); err != nil {
+			t.Fatalf("got error adding %+v: %v", p, err)
+		}
+		ps[i].ID = i + 1
+	}
+	tests := []struct {
+		order  store.Order
+		sortFn func([]person.Person)
+	}{
+		{store.OrderGenderLastNameAsc, person.SortGenderLastNameAsc},
+		{store.OrderBirthdateAsc, person.SortBirthdateAsc},
+		{store.OrderLastNameDesc, person.SortLastNameDesc},
+	}
+	for _, test := range tests {
+		t.Run(string(test.order), func(t *testing.T) {
+			want := make([]person.Person, len(ps))
+			copy(want, ps)
+			test.sortFn(want)
+			got, err := s.ListSorted(test.order)
+			if err != nil {
+				t.Fatalf("got error listing: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got persons %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package store defines where the person records served by the API
+// are kept.
+package store
+
+import (
+	"errors"
+
+	"github.com/lag13/records/internal/person"
+)
+
+// ErrNotFound is returned when a record with the requested id does
+// not exist.
+var ErrNotFound = errors.New("record not found")
+
+// Store holds person records. Every record is identified by an id
+// which is assigned by the store when the record is added and is
+// never reused. The persons returned by a Store have their ID field
+// set. Implementations must be safe for concurrent use.
+type Store interface {
+	// Add stores a person, ignoring its ID field, and returns the
+	// id it was given.
+	Add(p person.Person) (int, error)
+	// AddAll stores every person, or none of them if something
+	// goes wrong, and returns the ids they were given.
+	AddAll(ps []person.Person) ([]int, error)
+	// List returns every stored person in the order they were
+	// added.
+	List() ([]person.Person, error)
+	// Get returns the person with the given id or ErrNotFound.
+	Get(id int) (person.Person, error)
+	// Update replaces the person with the given id, the ID field
+	// of p is ignored, or returns ErrNotFound.
+	Update(id int, p person.Person) error
+	// Modify replaces the person with the given id by what fn
+	// returns when given the current version, or returns
+	// ErrNotFound. Nothing else can change the person in between
+	// so concurrent modifications are not lost. If fn returns an
+	// error nothing is changed and that error is returned.
+	Modify(id int, fn func(person.Person) (person.Person, error)) error
+	// Delete removes the person with the given id or returns
+	// ErrNotFound.
+	Delete(id int) error
+	// Count returns the number of stored persons.
+	Count() (int, error)
+}
+
+// Order names an order that a Sorter can return records in.
+type Order string
+
+// These orders match the sorting functions of the same name in the
+// person package.
+const (
+	OrderGenderLastNameAsc Order = "gender-lastname-asc"
+	OrderBirthdateAsc      Order = "birthdate-asc"
+	OrderLastNameDesc      Order = "lastname-desc"
+)
+
+// Sorter is implemented by stores which can return their records
+// already sorted, typically because they maintain an index.
+type Sorter interface {
+	// ListSorted returns every stored person in the given order.
+	ListSorted(o Order) ([]person.Person, error)
+}
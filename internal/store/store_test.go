@@ -0,0 +1,85 @@
+package store_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/store"
+)
+
+func TestModify(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	l := openLog(t, filepath.Join(dir, "log"))
+	defer closeLog(t, l)
+	sq, err := store.OpenSQLite(filepath.Join(dir, store.SQLiteFileName))
+	if err != nil {
+		t.Fatalf("got error opening database: %v", err)
+	}
+	defer func() { _ = sq.Close() }()
+	indexed, err := store.NewIndexed(store.NewMemory(), store.OrderLastNameDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		s    store.Store
+	}{
+		{name: "memory", s: store.NewMemory()},
+		{name: "log", s: l},
+		{name: "sqlite", s: sq},
+		{name: "indexed", s: indexed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, err := test.s.Add(hobbits[0])
+			if err != nil {
+				t.Fatalf("got error adding: %v", err)
+			}
+			if got, want := test.s.Modify(id+100, func(p person.Person) (person.Person, error) { return p, nil }), store.ErrNotFound; got != want {
+				t.Errorf("modifying a missing id got error %v, want %v", got, want)
+			}
+			errRefused := errors.New("refused")
+			refuse := func(p person.Person) (person.Person, error) {
+				p.LastName = "Changed"
+				return p, errRefused
+			}
+			if got, want := test.s.Modify(id, refuse), errRefused; got != want {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+			// Every modification appends to the first name so
+			// if any of them is lost the name comes up short.
+			const n = 20
+			var wg sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := test.s.Modify(id, func(p person.Person) (person.Person, error) {
+						p.FirstName += "!"
+						return p, nil
+					})
+					if err != nil {
+						t.Errorf("got error modifying: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			p, err := test.s.Get(id)
+			if err != nil {
+				t.Fatalf("got error getting: %v", err)
+			}
+			want := hobbits[0]
+			want.ID = id
+			want.FirstName += strings.Repeat("!", n)
+			if got := p; got != want {
+				t.Errorf("got person %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package where
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	// tokWord is a field name, a keyword like "in" or an unquoted
+	// value like a date.
+	tokWord
+	// tokString is a double quoted string. Its text has the quotes
+	// removed and escapes resolved.
+	tokString
+	// tokOp is an operator or punctuation.
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// col is the column, counting from 1, the token starts at.
+	col int
+}
+
+// describe returns how the token is referred to in error messages.
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "the end of the expression"
+	case tokString:
+		return "string " + quote(t.text)
+	}
+	return quote(t.text)
+}
+
+func quote(s string) string {
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+// ops are the operators, longest first so that "<=" is not read as
+// "<" followed by "=".
+var ops = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", ","}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '/'
+}
+
+// lex splits an expression into tokens. The last token is always
+// tokEOF.
+func lex(s string) ([]token, error) {
+	rs := []rune(s)
+	tokens := []token{}
+	i := 0
+	for i < len(rs) {
+		r := rs[i]
+		col := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			var b strings.Builder
+			i++
+			for {
+				if i == len(rs) {
+					return nil, &ParseError{Col: col, Msg: "the string is missing its closing quote"}
+				}
+				if rs[i] == '"' {
+					i++
+					break
+				}
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+				}
+				b.WriteRune(rs[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), col: col})
+		case isWordRune(r):
+			start := i
+			for i < len(rs) && isWordRune(rs[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: string(rs[start:i]), col: col})
+		default:
+			op := ""
+			for _, candidate := range ops {
+				if strings.HasPrefix(string(rs[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &ParseError{Col: col, Msg: "unexpected character " + quote(string(r))}
+			}
+			i += len([]rune(op))
+			tokens = append(tokens, token{kind: tokOp, text: op, col: col})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, col: len(rs) + 1}), nil
+}
@@ -0,0 +1,283 @@
+// Package where parses filter expressions like
+//
+//	gender == "Female" && birthdate < 1990-01-01 || color in ("red", "blue")
+//
+// into a predicate over people. A comparison is a field name, an
+// operator (==, !=, <, <=, >, >= or in) and a value. Text fields are
+// compared with double quoted strings, ignoring case, and birthdate
+// is compared with dates like 1990-01-01. Comparisons can be combined
+// with && (and), || (or) and ! (not) where && binds tighter than ||
+// and parentheses group.
+package where
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lag13/records/internal/fields"
+	"github.com/lag13/records/internal/person"
+)
+
+// DateLayout is the format dates are written in.
+const DateLayout = "2006-01-02"
+
+// ParseError describes what is wrong with an expression and where.
+type ParseError struct {
+	// Col is the column, counting from 1, of the problem.
+	Col int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Col, e.Msg)
+}
+
+// textFields are the text fields which can be compared.
+var textFields = map[string]func(person.Person) string{
+	fields.LastName:      func(p person.Person) string { return p.LastName },
+	fields.FirstName:     func(p person.Person) string { return p.FirstName },
+	fields.Gender:        func(p person.Person) string { return p.Gender },
+	fields.FavoriteColor: func(p person.Person) string { return p.FavoriteColor },
+}
+
+// FieldNames returns the names of the fields which can be compared,
+// which is every field of a person.
+func FieldNames() []string {
+	return append([]string{}, fields.Person...)
+}
+
+// comparisons are the operators which compare a field with a value,
+// keyed by operator, and whether the result of comparing the two
+// satisfies them.
+var comparisons = map[string]func(cmp int) bool{
+	"==": func(cmp int) bool { return cmp == 0 },
+	"!=": func(cmp int) bool { return cmp != 0 },
+	"<":  func(cmp int) bool { return cmp < 0 },
+	"<=": func(cmp int) bool { return cmp <= 0 },
+	">":  func(cmp int) bool { return cmp > 0 },
+	">=": func(cmp int) bool { return cmp >= 0 },
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	match func(person.Person) bool
+}
+
+// Parse parses an expression. The error is a *ParseError.
+func Parse(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return Expr{}, err
+	}
+	ps := &parser{tokens: tokens}
+	if ps.peek().kind == tokEOF {
+		return Expr{}, &ParseError{Col: 1, Msg: "the expression is empty"}
+	}
+	match, err := ps.parseOr()
+	if err != nil {
+		return Expr{}, err
+	}
+	if t := ps.peek(); t.kind != tokEOF {
+		return Expr{}, ps.errorf(t, "expected && or || but found %s", t.describe())
+	}
+	return Expr{match: match}, nil
+}
+
+// Match returns true if the person satisfies the expression.
+func (e Expr) Match(p person.Person) bool {
+	return e.match(p)
+}
+
+// Apply returns the people who satisfy the expression.
+func (e Expr) Apply(ps []person.Person) []person.Person {
+	kept := []person.Person{}
+	for _, p := range ps {
+		if e.Match(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (ps *parser) peek() token {
+	return ps.tokens[ps.pos]
+}
+
+func (ps *parser) next() token {
+	t := ps.tokens[ps.pos]
+	if t.kind != tokEOF {
+		ps.pos++
+	}
+	return t
+}
+
+func (ps *parser) isOp(op string) bool {
+	t := ps.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (ps *parser) errorf(t token, format string, args ...interface{}) error {
+	return &ParseError{Col: t.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses operands separated by ||.
+func (ps *parser) parseOr() (func(person.Person) bool, error) {
+	left, err := ps.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for ps.isOp("||") {
+		ps.next()
+		right, err := ps.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(p person.Person) bool { return l(p) || right(p) }
+	}
+	return left, nil
+}
+
+// parseAnd parses operands separated by &&.
+func (ps *parser) parseAnd() (func(person.Person) bool, error) {
+	left, err := ps.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for ps.isOp("&&") {
+		ps.next()
+		right, err := ps.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(p person.Person) bool { return l(p) && right(p) }
+	}
+	return left, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a
+// comparison.
+func (ps *parser) parseUnary() (func(person.Person) bool, error) {
+	if ps.isOp("!") {
+		ps.next()
+		inner, err := ps.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(p person.Person) bool { return !inner(p) }, nil
+	}
+	if ps.isOp("(") {
+		open := ps.next()
+		inner, err := ps.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !ps.isOp(")") {
+			t := ps.peek()
+			return nil, ps.errorf(t, `expected ")" to close the "(" at column %d but found %s`, open.col, t.describe())
+		}
+		ps.next()
+		return inner, nil
+	}
+	return ps.parseComparison()
+}
+
+// parseComparison parses a field followed by an operator and a value
+// or, for the in operator, a parenthesized list of values.
+func (ps *parser) parseComparison() (func(person.Person) bool, error) {
+	fieldTok := ps.next()
+	if fieldTok.kind != tokWord {
+		return nil, ps.errorf(fieldTok, "expected a field name but found %s", fieldTok.describe())
+	}
+	name, _ := fields.Lookup(fieldTok.text, FieldNames())
+	// cmpFn turns a value into a function comparing a person's
+	// field with it.
+	var cmpFn func(value token) (func(person.Person) int, error)
+	if field, ok := textFields[name]; ok {
+		cmpFn = func(value token) (func(person.Person) int, error) {
+			if value.kind != tokString {
+				return nil, ps.errorf(value, `expected a quoted string like "red" but found %s`, value.describe())
+			}
+			want := strings.ToLower(value.text)
+			return func(p person.Person) int {
+				return strings.Compare(strings.ToLower(field(p)), want)
+			}, nil
+		}
+	} else if name == fields.Birthdate {
+		cmpFn = func(value token) (func(person.Person) int, error) {
+			if value.kind != tokWord && value.kind != tokString {
+				return nil, ps.errorf(value, "expected a date like 1990-01-01 but found %s", value.describe())
+			}
+			want, err := time.Parse(DateLayout, value.text)
+			if err != nil {
+				return nil, ps.errorf(value, "expected a date like 1990-01-01 but found %s", value.describe())
+			}
+			return func(p person.Person) int {
+				switch {
+				case p.DateOfBirth.Before(want):
+					return -1
+				case p.DateOfBirth.After(want):
+					return 1
+				}
+				return 0
+			}, nil
+		}
+	} else {
+		return nil, ps.errorf(fieldTok, "unknown field %s, allowed fields are %s", fieldTok.describe(), strings.Join(FieldNames(), ", "))
+	}
+	opTok := ps.next()
+	if opTok.kind == tokWord && strings.ToLower(opTok.text) == "in" {
+		return ps.parseIn(cmpFn)
+	}
+	satisfied, ok := comparisons[opTok.text]
+	if opTok.kind != tokOp || !ok {
+		return nil, ps.errorf(opTok, "expected a comparison operator (==, !=, <, <=, >, >= or in) but found %s", opTok.describe())
+	}
+	cmp, err := cmpFn(ps.next())
+	if err != nil {
+		return nil, err
+	}
+	return func(p person.Person) bool { return satisfied(cmp(p)) }, nil
+}
+
+// parseIn parses the list of values after the in operator.
+func (ps *parser) parseIn(cmpFn func(value token) (func(person.Person) int, error)) (func(person.Person) bool, error) {
+	if !ps.isOp("(") {
+		t := ps.peek()
+		return nil, ps.errorf(t, `expected "(" to start the list of values but found %s`, t.describe())
+	}
+	open := ps.next()
+	cmps := []func(person.Person) int{}
+	for {
+		cmp, err := cmpFn(ps.next())
+		if err != nil {
+			return nil, err
+		}
+		cmps = append(cmps, cmp)
+		if ps.isOp(",") {
+			ps.next()
+			continue
+		}
+		if ps.isOp(")") {
+			ps.next()
+			break
+		}
+		t := ps.peek()
+		return nil, ps.errorf(t, `expected "," or ")" to close the "(" at column %d but found %s`, open.col, t.describe())
+	}
+	return func(p person.Person) bool {
+		for _, cmp := range cmps {
+			if cmp(p) == 0 {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
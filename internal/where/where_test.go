@@ -0,0 +1,89 @@
+package where_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lag13/records/internal/person"
+	"github.com/lag13/records/internal/where"
+)
+
+func errToStr(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestParseAndApply(t *testing.T) {
+	ps := []person.Person{
+		{ID: 1, LastName: "Smith", FirstName: "Anne", Gender: "Female", FavoriteColor: "Blue", DateOfBirth: time.Date(1985, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, LastName: "Smithers", FirstName: "Waylon", Gender: "Male", FavoriteColor: "red", DateOfBirth: time.Date(1970, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 3, LastName: "Jones", FirstName: "Beth", Gender: "female", FavoriteColor: "Green", DateOfBirth: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 4, LastName: "Smyth", FirstName: "Carol", Gender: "female", FavoriteColor: "Blue", DateOfBirth: time.Date(1995, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	tests := []struct {
+		expr    string
+		wantIDs []int
+	}{
+		{`gender == "female"`, []int{1, 3, 4}},
+		{`gender != "FEMALE"`, []int{2}},
+		{`birthdate < 1990-01-01`, []int{1, 2}},
+		{`birthdate <= "1990-01-01"`, []int{1, 2, 3}},
+		{`birthdate>1990-01-01`, []int{4}},
+		{`birthdate >= 1990-01-01`, []int{3, 4}},
+		{`last_name > "smith"`, []int{2, 4}},
+		{`color in ("red","blue")`, []int{1, 2, 4}},
+		{`FavoriteColor IN ( "green" )`, []int{3}},
+		{`birthdate in (1970-03-01, 1995-01-01)`, []int{2, 4}},
+		{`gender == "Female" && birthdate < 1990-01-01 || color in ("red","blue")`, []int{1, 2, 4}},
+		{`gender == "Female" && (birthdate < 1990-01-01 || color in ("red","green"))`, []int{1, 3}},
+		{`!(gender == "female") || !firstname == "anne"`, []int{2, 3, 4}},
+		{`lastname == "Smith" || lastname == "Jones" && color == "blue"`, []int{1}},
+		{`firstname == "Wayl\"on"`, []int{}},
+	}
+	for _, test := range tests {
+		e, err := where.Parse(test.expr)
+		if err != nil {
+			t.Errorf("%s: got error %v", test.expr, err)
+			continue
+		}
+		ids := []int{}
+		for _, p := range e.Apply(ps) {
+			ids = append(ids, p.ID)
+		}
+		if got, want := ids, test.wantIDs; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got ids %v, want %v", test.expr, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "column 1: the expression is empty"},
+		{"   ", "column 1: the expression is empty"},
+		{`gender == "female`, "column 11: the string is missing its closing quote"},
+		{`gender = "female"`, `column 8: unexpected character "="`},
+		{`height > "tall"`, `column 1: unknown field "height", allowed fields are last_name, first_name, gender, favorite_color, birthdate`},
+		{`gender "female"`, `column 8: expected a comparison operator (==, !=, <, <=, >, >= or in) but found string "female"`},
+		{`gender == female`, `column 11: expected a quoted string like "red" but found "female"`},
+		{`birthdate < 1990-13-01`, `column 13: expected a date like 1990-01-01 but found "1990-13-01"`},
+		{`birthdate < (`, `column 13: expected a date like 1990-01-01 but found "("`},
+		{`gender == "female" &&`, "column 22: expected a field name but found the end of the expression"},
+		{`(gender == "female"`, `column 20: expected ")" to close the "(" at column 1 but found the end of the expression`},
+		{`color in "red"`, `column 10: expected "(" to start the list of values but found string "red"`},
+		{`color in ("red" "blue")`, `column 17: expected "," or ")" to close the "(" at column 10 but found string "blue"`},
+		{`gender == "female" gender == "male"`, `column 20: expected && or || but found "gender"`},
+		{`lastname == "Zoë" &&& x`, `column 21: unexpected character "&"`},
+	}
+	for _, test := range tests {
+		_, err := where.Parse(test.expr)
+		if got, want := errToStr(err), test.wantErr; got != want {
+			t.Errorf("%s: got error %q, want %q", test.expr, got, want)
+		}
+	}
+}